@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+	"github.com/Server-Manager-cloud/cronjobs/spool"
+)
+
+// batchWrite is a record created while batching.
+type batchWrite struct {
+	collection string
+	payload    interface{}
+}
+
+// batch collects the records created during one run.
+type batch struct {
+	mu     sync.Mutex
+	writes []batchWrite
+}
+
+func (b *batch) add(collection string, payload interface{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.writes = append(b.writes, batchWrite{collection: collection, payload: payload})
+}
+
+func (b *batch) take() []batchWrite {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	writes := b.writes
+	b.writes = nil
+	return writes
+}
+
+// Batch returns a copy of env for one run, with its own RunID. It collects
+// the records its collectors create, e.g. one per mount, until Flush sends
+// them in a single batch request instead of one request each. Updates are
+// still sent right away.
+func (env *Env) Batch() *Env {
+	batched := *env
+	batched.RunID = newRunID()
+	batched.batch = &batch{}
+	return &batched
+}
+
+// Flush sends the records collected since Batch, in batch requests of at
+// most pocketbase.MaxBatchRequests records. The records are created one by
+// one when PocketBase has no batch API or rejects a batch.
+func (env *Env) Flush(ctx context.Context) error {
+	if env.batch == nil {
+		return nil
+	}
+
+	writes := env.batch.take()
+	var errs []error
+	for len(writes) > 0 {
+		n := min(len(writes), pocketbase.MaxBatchRequests)
+		if err := env.sendBatch(ctx, writes[:n]); err != nil {
+			errs = append(errs, err)
+		}
+		writes = writes[n:]
+	}
+	return errors.Join(errs...)
+}
+
+// sendBatch creates the records of writes in a single batch request when
+// possible. While older writes are queued they are queued one by one.
+func (env *Env) sendBatch(ctx context.Context, writes []batchWrite) error {
+	if len(writes) > 1 && !env.noBatch.Load() && !env.queued() {
+		requests := make([]pocketbase.BatchRequest, 0, len(writes))
+		for _, w := range writes {
+			requests = append(requests, pocketbase.CreateRequest(w.collection, w.payload))
+		}
+
+		_, err := env.Client.Batch(ctx, requests)
+		switch {
+		case err == nil:
+			log.Printf("%d records successfully sent in one batch!", len(writes))
+			return nil
+		case pocketbase.IsBatchUnsupported(err):
+			log.Printf("PocketBase batch API unavailable, creating records one by one: %v", err)
+			env.noBatch.Store(true)
+		case pocketbase.IsRetryable(err):
+			var errs []error
+			for _, w := range writes {
+				op := spool.Op{Action: spool.ActionCreate, Collection: w.collection}
+				if err := env.spoolOnFailure(err, op, w.payload); err != nil {
+					errs = append(errs, fmt.Errorf("failed to create %s record: %v", w.collection, err))
+				}
+			}
+			return errors.Join(errs...)
+		default:
+			log.Printf("Batch of %d records rejected, creating them one by one: %v", len(writes), err)
+		}
+	}
+
+	var errs []error
+	for _, w := range writes {
+		err := env.send(spool.Op{Action: spool.ActionCreate, Collection: w.collection}, w.payload, func() error {
+			return env.createRecord(ctx, w.collection, w.payload)
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create %s record: %v", w.collection, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// sendRequests sends requests in a single batch request, or one by one when
+// PocketBase has no batch API. Unlike Flush nothing is queued when
+// PocketBase is unreachable.
+func (env *Env) sendRequests(ctx context.Context, requests []pocketbase.BatchRequest) error {
+	if len(requests) > 1 && !env.noBatch.Load() {
+		_, err := env.Client.Batch(ctx, requests)
+		if !pocketbase.IsBatchUnsupported(err) {
+			return err
+		}
+		log.Printf("PocketBase batch API unavailable, sending requests one by one: %v", err)
+		env.noBatch.Store(true)
+	}
+
+	for _, request := range requests {
+		if err := env.Client.Send(ctx, request); err != nil {
+			return err
+		}
+	}
+	return nil
+}
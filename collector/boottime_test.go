@@ -0,0 +1,23 @@
+package collector
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseBootTime(t *testing.T) {
+	stat := "cpu  10 0 20 300 0 0 0 0 0 0\nintr 1 0\nbtime 1714564800\nprocesses 42\n"
+	got, err := parseBootTime(stat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Unix(1714564800, 0); !got.Equal(want) {
+		t.Errorf("parseBootTime() = %v, want %v", got, want)
+	}
+
+	for _, stat := range []string{"cpu  10 0 20 300\n", "btime soon\n"} {
+		if _, err := parseBootTime(stat); err == nil {
+			t.Errorf("parseBootTime(%q) succeeded, want an error", stat)
+		}
+	}
+}
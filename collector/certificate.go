@@ -0,0 +1,89 @@
+package collector
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math"
+	"time"
+)
+
+// certificateSummary is what the admin panel shows about a certificate.
+type certificateSummary struct {
+	Domains   []string
+	Issuer    string
+	KeyType   string
+	NotBefore time.Time
+	NotAfter  time.Time
+}
+
+// summarizeCertificate extracts the fields we report from a certificate.
+func summarizeCertificate(cert *x509.Certificate) certificateSummary {
+	domains := cert.DNSNames
+	if len(domains) == 0 && cert.Subject.CommonName != "" {
+		domains = []string{cert.Subject.CommonName}
+	}
+
+	issuer := cert.Issuer.CommonName
+	if issuer == "" {
+		issuer = cert.Issuer.String()
+	}
+
+	return certificateSummary{
+		Domains:   domains,
+		Issuer:    issuer,
+		KeyType:   describeKey(cert),
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}
+}
+
+// daysRemaining returns the number of whole days until the certificate
+// expires, negative once it has expired.
+func (s certificateSummary) daysRemaining(now time.Time) int {
+	return int(math.Floor(s.NotAfter.Sub(now).Hours() / 24))
+}
+
+// describeKey returns the key algorithm and size, e.g. "RSA 2048" or
+// "ECDSA P-256".
+func describeKey(cert *x509.Certificate) string {
+	switch key := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return fmt.Sprintf("RSA %d", key.N.BitLen())
+	case *ecdsa.PublicKey:
+		return "ECDSA " + key.Curve.Params().Name
+	case ed25519.PublicKey:
+		return "Ed25519"
+	default:
+		return cert.PublicKeyAlgorithm.String()
+	}
+}
+
+// parsePEMCertificates decodes every CERTIFICATE block in data.
+func parsePEMCertificates(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse certificate: %v", err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("no certificate found")
+	}
+	return certs, nil
+}
@@ -0,0 +1,66 @@
+package collector
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/internal/store"
+)
+
+// sampleTimeLayout is how the sampledAt field of metric records is written,
+// in UTC with millisecond precision.
+const sampleTimeLayout = "2006-01-02T15:04:05.000Z"
+
+// sampleClock hands out the collection timestamps of a server. Every
+// timestamp is at least a millisecond after the previous one, also across
+// runs and when the system clock is set back, so the samples of a server
+// sort in the order they were taken.
+type sampleClock struct {
+	state store.Store
+
+	mu     sync.Mutex
+	last   time.Time
+	loaded bool
+}
+
+// next returns the timestamp of a sample taken now.
+func (c *sampleClock) next() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.loaded {
+		if _, err := c.state.Load("clock", &c.last); err != nil {
+			log.Printf("Ignoring previous sample time: %v", err)
+		}
+		c.loaded = true
+	}
+
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	if !now.After(c.last) {
+		now = c.last.Add(time.Millisecond)
+	}
+	c.last = now
+
+	if err := c.state.Save("clock", now); err != nil {
+		log.Printf("Error saving sample time: %v", err)
+	}
+	return now
+}
+
+// newRunID returns a random ID grouping the samples of one run.
+func newRunID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return time.Now().UTC().Format("20060102150405.000000000")
+	}
+	return hex.EncodeToString(id)
+}
+
+// sampledAt returns the collection timestamp of the data being submitted,
+// formatted for the sampledAt field.
+func (env *Env) sampledAt() string {
+	return env.SampledAt.Format(sampleTimeLayout)
+}
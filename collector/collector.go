@@ -0,0 +1,181 @@
+// Package collector contains the collectors that gather information about
+// this server and submit it to PocketBase.
+package collector
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/config"
+	"github.com/Server-Manager-cloud/cronjobs/internal/store"
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+	"github.com/Server-Manager-cloud/cronjobs/spool"
+)
+
+// Env is the shared state every collector runs with.
+type Env struct {
+	Config   *config.Config
+	ServerID string
+	Client   *pocketbase.Client
+
+	// State persists data between runs, e.g. the previous counter sample
+	// that rates are computed against.
+	State store.Store
+
+	// Spool queues writes while PocketBase is unreachable, nil when
+	// disabled.
+	Spool *spool.Spool
+
+	// RunID groups the samples of one run, e.g. all collectors of a cron
+	// tick. It is set by Batch, and by Run when empty.
+	RunID string
+
+	// SampledAt is when the data being submitted was collected, set by Run.
+	SampledAt time.Time
+
+	// batch collects created records until Flush, nil unless env was
+	// returned by Batch.
+	batch *batch
+
+	// noBatch is set once PocketBase turned out to have no batch API.
+	noBatch *atomic.Bool
+
+	clock *sampleClock
+}
+
+// NewEnv builds the collector environment from smc.json and the variables
+// loaded from .env.
+func NewEnv(cfg *config.Config) (*Env, error) {
+	// Get the server ID from the environment variable
+	serverID := os.Getenv("ID")
+	if serverID == "" {
+		return nil, fmt.Errorf("ID environment variable not set")
+	}
+
+	client := pocketbase.NewClient(cfg.Domain)
+	auth, err := pocketbase.AuthFromEnv()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load PocketBase credentials: %v", err)
+	}
+	client.Auth = auth
+	configureClient(client, cfg.PocketBase)
+
+	env := &Env{
+		Config:   cfg,
+		ServerID: serverID,
+		Client:   client,
+		State:    store.Store{Dir: cfg.StateDir},
+		noBatch:  &atomic.Bool{},
+		clock:    &sampleClock{state: store.Store{Dir: cfg.StateDir}},
+	}
+	if !cfg.Spool.Disabled {
+		env.Spool = &spool.Spool{
+			Dir:        filepath.Join(cfg.StateDir, "spool"),
+			MaxEntries: cfg.Spool.MaxEntries,
+			MaxAge:     time.Duration(cfg.Spool.MaxAge),
+		}
+	}
+
+	return env, nil
+}
+
+// configureClient applies the timeout, retry and circuit breaker settings of
+// smc.json to client.
+func configureClient(client *pocketbase.Client, cfg config.PocketBaseConfig) {
+	if cfg.Timeout > 0 {
+		client.Timeout = time.Duration(cfg.Timeout)
+	}
+	if cfg.MaxAttempts > 0 {
+		client.Retry.MaxAttempts = cfg.MaxAttempts
+	}
+	if cfg.RetryDelay > 0 {
+		client.Retry.BaseDelay = time.Duration(cfg.RetryDelay)
+	}
+	if cfg.MaxRetryDelay > 0 {
+		client.Retry.MaxDelay = time.Duration(cfg.MaxRetryDelay)
+	}
+
+	switch {
+	case cfg.BreakerThreshold < 0:
+		client.Breaker = nil
+	case cfg.BreakerThreshold > 0:
+		client.Breaker.Threshold = cfg.BreakerThreshold
+	}
+	if client.Breaker != nil && cfg.BreakerCooldown > 0 {
+		client.Breaker.Cooldown = time.Duration(cfg.BreakerCooldown)
+	}
+}
+
+// Collector gathers one kind of information and submits it to PocketBase.
+type Collector interface {
+	// Name identifies the collector in smc.json and on the command line.
+	Name() string
+
+	// Collect gathers the data from this server.
+	Collect(ctx context.Context, env *Env) (interface{}, error)
+
+	// Submit sends the data returned by Collect to PocketBase.
+	Submit(ctx context.Context, env *Env, data interface{}) error
+}
+
+var registry = map[string]Collector{}
+
+// Register makes a collector available by its name. It panics when two
+// collectors share a name.
+func Register(c Collector) {
+	if _, exists := registry[c.Name()]; exists {
+		panic(fmt.Sprintf("collector %s registered twice", c.Name()))
+	}
+	registry[c.Name()] = c
+}
+
+// Get returns the collector registered under name.
+func Get(name string) (Collector, bool) {
+	c, ok := registry[name]
+	return c, ok
+}
+
+// All returns every registered collector, sorted by name.
+func All() []Collector {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	collectors := make([]Collector, 0, len(names))
+	for _, name := range names {
+		collectors = append(collectors, registry[name])
+	}
+	return collectors
+}
+
+// Run collects and submits the data of a single collector. Writes queued
+// while PocketBase was unreachable are sent first, so they keep their order.
+// The data is submitted with the time it was collected.
+func Run(ctx context.Context, env *Env, c Collector) error {
+	data, err := c.Collect(ctx, env)
+	if err != nil {
+		return fmt.Errorf("%s: failed to collect: %w", c.Name(), err)
+	}
+
+	sampled := *env
+	sampled.SampledAt = env.clock.next()
+	if sampled.RunID == "" {
+		sampled.RunID = newRunID()
+	}
+	env = &sampled
+
+	env.replay(ctx)
+
+	if err := c.Submit(ctx, env, data); err != nil {
+		return fmt.Errorf("%s: failed to submit: %w", c.Name(), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,302 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(cpuCollector{})
+}
+
+// cpuSampleInterval is how long Collect waits between two /proc/stat reads
+// when there is no usable sample from a previous run.
+const cpuSampleInterval = time.Second
+
+// cpuCollector reports the CPU usage to the cpu collection.
+type cpuCollector struct{}
+
+// cpuTimes holds the jiffy counters of one cpu line in /proc/stat.
+type cpuTimes struct {
+	User    uint64 `json:"user"`
+	Nice    uint64 `json:"nice"`
+	System  uint64 `json:"system"`
+	Idle    uint64 `json:"idle"`
+	Iowait  uint64 `json:"iowait"`
+	Irq     uint64 `json:"irq"`
+	Softirq uint64 `json:"softirq"`
+	Steal   uint64 `json:"steal"`
+}
+
+// total returns the sum of all counters. Guest time is already included in
+// user and nice, so it is not added again.
+func (t cpuTimes) total() uint64 {
+	return t.User + t.Nice + t.System + t.Idle + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
+// cpuSample is one read of /proc/stat, persisted between runs.
+type cpuSample struct {
+	Time     time.Time           `json:"time"`
+	BootTime int64               `json:"bootTime"`
+	Total    cpuTimes            `json:"total"`
+	Cores    map[string]cpuTimes `json:"cores"`
+}
+
+// cpuModes is the share of time spent in each mode, in percent.
+type cpuModes struct {
+	Usage   float64 `json:"usage"`
+	User    float64 `json:"user"`
+	Nice    float64 `json:"nice"`
+	System  float64 `json:"system"`
+	Idle    float64 `json:"idle"`
+	Iowait  float64 `json:"iowait"`
+	Irq     float64 `json:"irq"`
+	Softirq float64 `json:"softirq"`
+	Steal   float64 `json:"steal"`
+}
+
+// coreUsage is the utilisation of a single core.
+type coreUsage struct {
+	Core string `json:"core"`
+	cpuModes
+}
+
+// cpuUsage is the utilisation between two samples.
+type cpuUsage struct {
+	cpuModes
+	Cores  []coreUsage
+	Period time.Duration
+}
+
+func (cpuCollector) Name() string { return "cpu" }
+
+// Collect computes the CPU usage since the previous run. Without a usable
+// previous sample (first run, reboot, or a run less than a second ago) it
+// takes two samples one second apart instead.
+func (cpuCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	var previous cpuSample
+	found, err := env.State.Load("cpu", &previous)
+	if err != nil {
+		log.Printf("Ignoring previous CPU sample: %v", err)
+	}
+
+	current, err := readCPUSample()
+	if err != nil {
+		return nil, err
+	}
+
+	if !found || previous.BootTime != current.BootTime || current.Time.Sub(previous.Time) < cpuSampleInterval {
+		previous = current
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(cpuSampleInterval):
+		}
+
+		current, err = readCPUSample()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := env.State.Save("cpu", current); err != nil {
+		log.Printf("Error saving CPU sample: %v", err)
+	}
+
+	return getCPUUsage(previous, current), nil
+}
+
+func (cpuCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	usage := data.(cpuUsage)
+
+	// Send the CPU usage data to PocketBase
+	err := sendUsageToPocketBase(ctx, env, usage)
+	if err != nil {
+		return err
+	}
+
+	log.Printf("CPU usage successfully reported! Current usage: %.2f%%", usage.Usage)
+	return nil
+}
+
+// readCPUSample reads the aggregate and per-core counters from /proc/stat.
+func readCPUSample() (cpuSample, error) {
+	// Read /proc/stat
+	data, err := os.ReadFile("/proc/stat")
+	if err != nil {
+		return cpuSample{}, fmt.Errorf("failed to read /proc/stat: %v", err)
+	}
+
+	sample := cpuSample{
+		Time:  time.Now(),
+		Cores: map[string]cpuTimes{},
+	}
+	foundTotal := false
+
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		if strings.HasPrefix(fields[0], "cpu") {
+			times, err := parseCPUTimes(fields[1:])
+			if err != nil {
+				return cpuSample{}, fmt.Errorf("failed to parse %s: %v", fields[0], err)
+			}
+
+			if fields[0] == "cpu" {
+				sample.Total = times
+				foundTotal = true
+			} else {
+				sample.Cores[fields[0]] = times
+			}
+		}
+	}
+
+	if !foundTotal {
+		return cpuSample{}, fmt.Errorf("cpu data not found in /proc/stat")
+	}
+
+	// The boot time tells a reboot since the previous sample
+	bootTime, err := parseBootTime(string(data))
+	if err != nil {
+		return cpuSample{}, err
+	}
+	sample.BootTime = bootTime.Unix()
+
+	return sample, nil
+}
+
+// parseCPUTimes parses the counters of a cpu line. Old kernels do not report
+// irq, softirq and steal, which are then left at zero.
+func parseCPUTimes(fields []string) (cpuTimes, error) {
+	if len(fields) < 4 {
+		return cpuTimes{}, fmt.Errorf("unexpected format in /proc/stat")
+	}
+
+	values := make([]uint64, 8)
+	for i := 0; i < len(values) && i < len(fields); i++ {
+		value, err := strconv.ParseUint(fields[i], 10, 64)
+		if err != nil {
+			return cpuTimes{}, fmt.Errorf("failed to parse CPU time: %v", err)
+		}
+		values[i] = value
+	}
+
+	return cpuTimes{
+		User:    values[0],
+		Nice:    values[1],
+		System:  values[2],
+		Idle:    values[3],
+		Iowait:  values[4],
+		Irq:     values[5],
+		Softirq: values[6],
+		Steal:   values[7],
+	}, nil
+}
+
+// getCPUUsage computes the utilisation between two samples.
+func getCPUUsage(previous, current cpuSample) cpuUsage {
+	usage := cpuUsage{
+		cpuModes: cpuModesBetween(previous.Total, current.Total),
+		Period:   current.Time.Sub(previous.Time),
+	}
+
+	for core, times := range current.Cores {
+		prev, ok := previous.Cores[core]
+		if !ok {
+			continue
+		}
+		usage.Cores = append(usage.Cores, coreUsage{
+			Core:     core,
+			cpuModes: cpuModesBetween(prev, times),
+		})
+	}
+
+	// Order cores numerically (cpu2 before cpu10)
+	sortCores(usage.Cores)
+
+	return usage
+}
+
+// cpuModesBetween converts counter deltas into percentages. Usage counts
+// everything except idle and iowait as busy.
+func cpuModesBetween(previous, current cpuTimes) cpuModes {
+	total := float64(current.total()) - float64(previous.total())
+	if total <= 0 {
+		return cpuModes{Idle: 100}
+	}
+
+	percent := func(prev, cur uint64) float64 {
+		if cur < prev {
+			return 0
+		}
+		return math.Round(float64(cur-prev)/total*10000) / 100
+	}
+
+	modes := cpuModes{
+		User:    percent(previous.User, current.User),
+		Nice:    percent(previous.Nice, current.Nice),
+		System:  percent(previous.System, current.System),
+		Idle:    percent(previous.Idle, current.Idle),
+		Iowait:  percent(previous.Iowait, current.Iowait),
+		Irq:     percent(previous.Irq, current.Irq),
+		Softirq: percent(previous.Softirq, current.Softirq),
+		Steal:   percent(previous.Steal, current.Steal),
+	}
+	modes.Usage = math.Max(0, math.Round((100-modes.Idle-modes.Iowait)*100)/100)
+
+	return modes
+}
+
+// sortCores orders cores by their number.
+func sortCores(cores []coreUsage) {
+	number := func(core string) int {
+		n, _ := strconv.Atoi(strings.TrimPrefix(core, "cpu"))
+		return n
+	}
+
+	sort.Slice(cores, func(i, j int) bool {
+		return number(cores[i].Core) < number(cores[j].Core)
+	})
+}
+
+// sendUsageToPocketBase sends the CPU usage data to the PocketBase API
+func sendUsageToPocketBase(ctx context.Context, env *Env, usage cpuUsage) error {
+	collection := "cpu"
+
+	// Create the payload with ID and CPU usage per mode and core
+	payload := map[string]interface{}{
+		"cpuUsage":      usage.Usage,
+		"user":          usage.User,
+		"nice":          usage.Nice,
+		"system":        usage.System,
+		"idle":          usage.Idle,
+		"iowait":        usage.Iowait,
+		"irq":           usage.Irq,
+		"softirq":       usage.Softirq,
+		"steal":         usage.Steal,
+		"cores":         usage.Cores,
+		"periodSeconds": math.Round(usage.Period.Seconds()),
+		"server":        env.ServerID,
+		"sampledAt":     env.sampledAt(),
+		"runId":         env.RunID,
+	}
+
+	err := env.create(ctx, collection, payload)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,225 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(diskIOCollector{})
+}
+
+// sectorSize is the unit of the sector counters in /proc/diskstats, which
+// is always 512 bytes regardless of the device.
+const sectorSize = 512
+
+// diskIOCollector reports I/O rates, latency and utilisation of every block
+// device to the disk_io collection, one record per device.
+type diskIOCollector struct{}
+
+// diskCounters are the /proc/diskstats counters of one block device. Times
+// are in milliseconds.
+type diskCounters struct {
+	Reads         uint64 `json:"reads"`
+	SectorsRead   uint64 `json:"sectorsRead"`
+	ReadTime      uint64 `json:"readTime"`
+	Writes        uint64 `json:"writes"`
+	SectorsWrite  uint64 `json:"sectorsWritten"`
+	WriteTime     uint64 `json:"writeTime"`
+	IOTime        uint64 `json:"ioTime"`
+	WeightedTime  uint64 `json:"weightedTime"`
+	InProgressIOs uint64 `json:"inProgress"`
+}
+
+// diskSample is one read of /proc/diskstats, persisted between runs.
+type diskSample struct {
+	Time    time.Time               `json:"time"`
+	Devices map[string]diskCounters `json:"devices"`
+}
+
+// diskIOUsage is the counters of a device and, when a previous sample
+// exists, the rates since then.
+type diskIOUsage struct {
+	Device   string
+	Counters diskCounters
+	Rates    map[string]float64
+	Period   time.Duration
+}
+
+func (diskIOCollector) Name() string { return "diskio" }
+
+func (d diskIOCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	current, err := readDiskSample()
+	if err != nil {
+		return nil, err
+	}
+
+	var previous diskSample
+	found, err := env.State.Load("diskio", &previous)
+	if err != nil {
+		log.Printf("Ignoring previous disk I/O sample: %v", err)
+	}
+
+	if err := env.State.Save("diskio", current); err != nil {
+		log.Printf("Error saving disk I/O sample: %v", err)
+	}
+
+	settings := env.Config.Collector(d.Name())
+	period := current.Time.Sub(previous.Time)
+
+	var usages []diskIOUsage
+	for device, counters := range current.Devices {
+		if !settings.Matches(device) || isPartition(device) {
+			continue
+		}
+
+		usage := diskIOUsage{Device: device, Counters: counters}
+		if prev, ok := previous.Devices[device]; found && ok && period > 0 {
+			usage.Rates = diskIORates(prev, counters, period)
+			usage.Period = period
+		}
+		usages = append(usages, usage)
+	}
+
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Device < usages[j].Device })
+	return usages, nil
+}
+
+func (diskIOCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	var errs []error
+	for _, usage := range data.([]diskIOUsage) {
+		c := usage.Counters
+		payload := map[string]interface{}{
+			"device":       usage.Device,
+			"reads":        c.Reads,
+			"writes":       c.Writes,
+			"bytesRead":    c.SectorsRead * sectorSize,
+			"bytesWritten": c.SectorsWrite * sectorSize,
+			"inProgress":   c.InProgressIOs,
+			"server":       env.ServerID,
+			"sampledAt":    env.sampledAt(),
+			"runId":        env.RunID,
+		}
+		for key, rate := range usage.Rates {
+			payload[key] = rate
+		}
+		if usage.Period > 0 {
+			payload["periodSeconds"] = math.Round(usage.Period.Seconds())
+		}
+
+		err := env.create(ctx, "disk_io", payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to send disk I/O for %s: %v", usage.Device, err))
+			continue
+		}
+
+		log.Printf("Disk I/O successfully reported for %s!", usage.Device)
+	}
+
+	return errors.Join(errs...)
+}
+
+// readDiskSample parses /proc/diskstats, e.g.
+//
+//	254 0 vda 10943 4065 1440122 7535 7108 11537 1085416 6177 0 2756 14148
+//
+// Newer kernels append discard and flush counters, which are ignored.
+func readDiskSample() (diskSample, error) {
+	data, err := os.ReadFile("/proc/diskstats")
+	if err != nil {
+		return diskSample{}, fmt.Errorf("failed to read /proc/diskstats: %v", err)
+	}
+
+	sample := diskSample{
+		Time:    time.Now(),
+		Devices: map[string]diskCounters{},
+	}
+
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 14 {
+			continue
+		}
+
+		var values [11]uint64
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[i+3], 10, 64)
+			if err != nil {
+				return diskSample{}, fmt.Errorf("failed to parse counters of %s: %v", fields[2], err)
+			}
+		}
+
+		sample.Devices[fields[2]] = diskCounters{
+			Reads:         values[0],
+			SectorsRead:   values[2],
+			ReadTime:      values[3],
+			Writes:        values[4],
+			SectorsWrite:  values[6],
+			WriteTime:     values[7],
+			InProgressIOs: values[8],
+			IOTime:        values[9],
+			WeightedTime:  values[10],
+		}
+	}
+
+	return sample, nil
+}
+
+// isPartition reports whether device is a partition rather than a whole
+// disk. Whole disks (including md and device-mapper devices) are listed in
+// /sys/block. Without sysfs every device is treated as a disk.
+func isPartition(device string) bool {
+	if _, err := os.Stat("/sys/block"); err != nil {
+		return false
+	}
+	_, err := os.Stat("/sys/block/" + strings.ReplaceAll(device, "/", "!"))
+	return err != nil
+}
+
+// diskIORates computes IOPS, throughput, average latency and utilisation
+// between two samples. Rates are omitted when the counters went backwards
+// (reboot or device re-attached).
+func diskIORates(previous, current diskCounters, period time.Duration) map[string]float64 {
+	if current.Reads < previous.Reads || current.Writes < previous.Writes ||
+		current.IOTime < previous.IOTime || current.WeightedTime < previous.WeightedTime {
+		return nil
+	}
+
+	seconds := period.Seconds()
+	round := func(v float64) float64 { return math.Round(v*100) / 100 }
+	delta := func(prev, cur uint64) float64 {
+		if cur < prev {
+			return 0
+		}
+		return float64(cur - prev)
+	}
+	latency := func(millis, ops float64) float64 {
+		if ops == 0 {
+			return 0
+		}
+		return round(millis / ops)
+	}
+
+	reads := delta(previous.Reads, current.Reads)
+	writes := delta(previous.Writes, current.Writes)
+	periodMillis := seconds * 1000
+
+	return map[string]float64{
+		"readIops":              round(reads / seconds),
+		"writeIops":             round(writes / seconds),
+		"readBytesPerSecond":    round(delta(previous.SectorsRead, current.SectorsRead) * sectorSize / seconds),
+		"writeBytesPerSecond":   round(delta(previous.SectorsWrite, current.SectorsWrite) * sectorSize / seconds),
+		"readLatencyMs":         latency(delta(previous.ReadTime, current.ReadTime), reads),
+		"writeLatencyMs":        latency(delta(previous.WriteTime, current.WriteTime), writes),
+		"utilizationPercentage": round(math.Min(100, delta(previous.IOTime, current.IOTime)/periodMillis*100)),
+		"averageQueueLength":    round(delta(previous.WeightedTime, current.WeightedTime) / periodMillis),
+	}
+}
@@ -0,0 +1,188 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/config"
+	"github.com/Server-Manager-cloud/cronjobs/dns"
+)
+
+// DNS statuses of a domain, stored in the dns_status field.
+const (
+	dnsStatusOK         = "ok"         // every address points at this server
+	dnsStatusPartial    = "partial"    // some addresses point elsewhere
+	dnsStatusDrift      = "drift"      // no address points at this server
+	dnsStatusUnresolved = "unresolved" // the domain has no A or AAAA records
+	dnsStatusUnknown    = "unknown"    // the addresses of this server are unknown
+	dnsStatusError      = "error"      // the lookup failed
+)
+
+// dnsRecords are the records of a domain as returned by the resolver.
+type dnsRecords struct {
+	A       []string `json:"a"`
+	AAAA    []string `json:"aaaa"`
+	CNAME   string   `json:"cname,omitempty"`
+	MX      []string `json:"mx"`
+	TXT     []string `json:"txt"`
+	Foreign []string `json:"foreign"` // A and AAAA addresses not of this server
+	Error   string   `json:"error,omitempty"`
+}
+
+// dnsCheck is the result of comparing the records of a domain with the
+// addresses of this server.
+type dnsCheck struct {
+	Status  string
+	Records dnsRecords
+	Checked time.Time
+}
+
+// domainDNS is the result of checking every domain of a certificate, with
+// the records keyed by domain.
+type domainDNS struct {
+	Status  string
+	Records map[string]dnsRecords
+	Checked time.Time
+}
+
+// publicAddresses returns the configured public addresses of this server, or
+// the global unicast, non-private addresses of its network interfaces.
+func publicAddresses(cfg config.DNSConfig) ([]net.IP, error) {
+	var addresses []net.IP
+	if len(cfg.PublicAddresses) > 0 {
+		for _, address := range cfg.PublicAddresses {
+			ip := net.ParseIP(address)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid public address %q", address)
+			}
+			addresses = append(addresses, ip)
+		}
+		return addresses, nil
+	}
+
+	interfaceAddresses, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list interface addresses: %v", err)
+	}
+	for _, address := range interfaceAddresses {
+		ipNet, ok := address.(*net.IPNet)
+		if !ok || !ipNet.IP.IsGlobalUnicast() || ipNet.IP.IsPrivate() {
+			continue
+		}
+		addresses = append(addresses, ipNet.IP)
+	}
+	return addresses, nil
+}
+
+// checkDNSRecords resolves the A, AAAA, CNAME, MX and TXT records of domain
+// and compares the addresses with the addresses of this server.
+func checkDNSRecords(ctx context.Context, resolver *dns.Client, domain string, own []net.IP) dnsCheck {
+	check := dnsCheck{Checked: time.Now().UTC()}
+	records := &check.Records
+
+	lookup := func(qtype uint16) []dns.Record {
+		result, err := resolver.Lookup(ctx, domain, qtype)
+		if err != nil && !errors.Is(err, dns.ErrNXDomain) && records.Error == "" {
+			records.Error = err.Error()
+		}
+		return result
+	}
+
+	for _, record := range lookup(dns.TypeA) {
+		records.A = append(records.A, record.Address.String())
+	}
+	for _, record := range lookup(dns.TypeAAAA) {
+		records.AAAA = append(records.AAAA, record.Address.String())
+	}
+	addressError := records.Error
+
+	for _, record := range lookup(dns.TypeCNAME) {
+		records.CNAME = record.Target
+	}
+	for _, record := range lookup(dns.TypeMX) {
+		records.MX = append(records.MX, fmt.Sprintf("%d %s", record.Preference, record.Target))
+	}
+	for _, record := range lookup(dns.TypeTXT) {
+		records.TXT = append(records.TXT, record.Text)
+	}
+	sort.Strings(records.A)
+	sort.Strings(records.AAAA)
+	sort.Strings(records.MX)
+	sort.Strings(records.TXT)
+
+	addresses := append(append([]string(nil), records.A...), records.AAAA...)
+	for _, address := range addresses {
+		if !containsIP(own, net.ParseIP(address)) {
+			records.Foreign = append(records.Foreign, address)
+		}
+	}
+
+	switch {
+	case addressError != "":
+		check.Status = dnsStatusError
+	case len(addresses) == 0:
+		check.Status = dnsStatusUnresolved
+	case len(own) == 0:
+		check.Status = dnsStatusUnknown
+	case len(records.Foreign) == 0:
+		check.Status = dnsStatusOK
+	case len(records.Foreign) < len(addresses):
+		check.Status = dnsStatusPartial
+	default:
+		check.Status = dnsStatusDrift
+	}
+	return check
+}
+
+// checkDomainDNS checks the records of every domain in names. The status is
+// only ok when all of them point at this server.
+func checkDomainDNS(ctx context.Context, resolver *dns.Client, names []string, own []net.IP) domainDNS {
+	result := domainDNS{Records: map[string]dnsRecords{}, Checked: time.Now().UTC()}
+
+	var statuses []string
+	for _, name := range names {
+		check := checkDNSRecords(ctx, resolver, name, own)
+		result.Records[name] = check.Records
+		statuses = append(statuses, check.Status)
+	}
+	result.Status = combineDNSStatus(statuses)
+	return result
+}
+
+// combineDNSStatus returns the status of a certificate from the statuses of
+// its domains.
+func combineDNSStatus(statuses []string) string {
+	counts := map[string]int{}
+	for _, status := range statuses {
+		counts[status]++
+	}
+
+	switch {
+	case counts[dnsStatusError] > 0:
+		return dnsStatusError
+	case counts[dnsStatusUnknown] > 0:
+		return dnsStatusUnknown
+	case counts[dnsStatusOK] == len(statuses):
+		return dnsStatusOK
+	case counts[dnsStatusUnresolved] == len(statuses):
+		return dnsStatusUnresolved
+	case counts[dnsStatusOK] == 0 && counts[dnsStatusPartial] == 0:
+		return dnsStatusDrift
+	default:
+		return dnsStatusPartial
+	}
+}
+
+// containsIP reports whether ip is one of addresses.
+func containsIP(addresses []net.IP, ip net.IP) bool {
+	for _, address := range addresses {
+		if address.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
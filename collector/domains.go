@@ -0,0 +1,233 @@
+package collector
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+)
+
+func init() {
+	Register(domainsCollector{})
+}
+
+// domainsCollector reports the certbot certificates on this server and their
+// expiry to the domains collection.
+type domainsCollector struct{}
+
+// domainRecord is a record of the PocketBase domains collection
+type domainRecord struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// certbotCertificate is a certificate managed by certbot.
+type certbotCertificate struct {
+	certificateSummary
+
+	// Name is the certbot certificate name, the directory in the live dir.
+	Name string
+
+	// Path is the certificate file the summary was read from.
+	Path string
+
+	// ChainError is why the chain from cert.pem and chain.pem does not
+	// verify against the system roots, empty when it does.
+	ChainError string
+}
+
+func (domainsCollector) Name() string { return "domains" }
+
+func (domainsCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	return getCertbotCertificates(env.Config.Domains.LiveDir)
+}
+
+func (d domainsCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	live := data.(*certbotLive)
+	if live == nil {
+		log.Println("Certbot is not installed, no certificates to report.")
+		return nil
+	}
+
+	// Send domains to PocketBase
+	errs := []error{sendDomainsToPocketBase(ctx, env, live.Certificates)}
+
+	// Mark the domains whose certificate no longer exists
+	deleteAfter := time.Duration(env.Config.Collector(d.Name()).DeleteGoneAfter)
+	errs = append(errs, reconcile(ctx, env, "domains", "name", live.Names, deleteAfter))
+
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+
+	log.Println("All domains successfully processed.")
+	return nil
+}
+
+// certbotLive is the content of certbot's live dir. Names lists every
+// certificate directory, also those whose certificate could not be read, so
+// that their domains are not marked gone.
+type certbotLive struct {
+	Names        []string
+	Certificates []certbotCertificate
+}
+
+// getCertbotCertificates reads the certificates certbot keeps in liveDir,
+// one subdirectory per certificate name. It returns nil when liveDir does
+// not exist.
+func getCertbotCertificates(liveDir string) (*certbotLive, error) {
+	entries, err := os.ReadDir(liveDir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil // certbot is not installed
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", liveDir, err)
+	}
+
+	live := &certbotLive{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue // e.g. the README certbot puts there
+		}
+		live.Names = append(live.Names, entry.Name())
+
+		certificate, err := readCertbotCertificate(filepath.Join(liveDir, entry.Name()))
+		if err != nil {
+			log.Printf("Error reading certificate %s: %v", entry.Name(), err)
+			continue
+		}
+		live.Certificates = append(live.Certificates, certificate)
+	}
+
+	sort.Slice(live.Certificates, func(i, j int) bool { return live.Certificates[i].Name < live.Certificates[j].Name })
+	return live, nil
+}
+
+// readCertbotCertificate reads cert.pem and chain.pem of one certificate.
+func readCertbotCertificate(dir string) (certbotCertificate, error) {
+	certPath := filepath.Join(dir, "cert.pem")
+	data, err := os.ReadFile(certPath)
+	if err != nil {
+		return certbotCertificate{}, err
+	}
+
+	certs, err := parsePEMCertificates(data)
+	if err != nil {
+		return certbotCertificate{}, fmt.Errorf("%s: %v", certPath, err)
+	}
+	leaf := certs[0]
+
+	certificate := certbotCertificate{
+		certificateSummary: summarizeCertificate(leaf),
+		Name:               filepath.Base(dir),
+		Path:               certPath,
+	}
+
+	// Verify the chain certbot serves with this certificate
+	intermediates := x509.NewCertPool()
+	chain, err := os.ReadFile(filepath.Join(dir, "chain.pem"))
+	if err == nil {
+		if chainCerts, err := parsePEMCertificates(chain); err == nil {
+			for _, cert := range chainCerts {
+				intermediates.AddCert(cert)
+			}
+		}
+	}
+
+	_, err = leaf.Verify(x509.VerifyOptions{Intermediates: intermediates})
+	if err != nil {
+		certificate.ChainError = err.Error()
+	}
+
+	return certificate, nil
+}
+
+// getDomainRecords returns the domain records of this server by name. Runs
+// of earlier versions created a new record every time, so of several
+// records with the same name the oldest is kept and the others are deleted.
+func getDomainRecords(ctx context.Context, client *pocketbase.Client, serverID string) (map[string]domainRecord, error) {
+	records, err := pocketbase.ListAll[domainRecord](ctx, client, "domains", pocketbase.ListOptions{
+		Filter: pocketbase.Filter("server = {:server}", map[string]interface{}{"server": serverID}),
+		Sort:   "created",
+		Fields: "id,name",
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve domains: %w", err)
+	}
+
+	byName := map[string]domainRecord{}
+	for _, record := range records {
+		if _, exists := byName[record.Name]; !exists {
+			byName[record.Name] = record
+			continue
+		}
+
+		if err := client.Delete(ctx, "domains", record.ID); err != nil {
+			log.Printf("Error deleting duplicate domain %s (%s): %v", record.Name, record.ID, err)
+			continue
+		}
+		log.Printf("Deleted duplicate domain %s (%s)", record.Name, record.ID)
+	}
+	return byName, nil
+}
+
+// sendDomainsToPocketBase sends the certificates to the PocketBase API. Each
+// certificate updates the record of this server with the same name, or
+// creates one.
+func sendDomainsToPocketBase(ctx context.Context, env *Env, certificates []certbotCertificate) error {
+	collection := "domains"
+	serverID := env.ServerID
+	now := time.Now()
+
+	// When PocketBase is unreachable every certificate is queued as upsert
+	records, err := getDomainRecords(ctx, env.Client, serverID)
+	if err != nil && !pocketbase.IsRetryable(err) {
+		return err
+	}
+
+	var errs []error
+	for _, certificate := range certificates {
+		// Prepare payload with the certificate info. The nameserver fields
+		// belong to the nameserver collector and are left untouched.
+		payload := map[string]interface{}{
+			"server":            serverID,
+			"name":              certificate.Name,
+			"certDomains":       certificate.Domains,
+			"certIssuer":        certificate.Issuer,
+			"certKeyType":       certificate.KeyType,
+			"certPath":          certificate.Path,
+			"certNotBefore":     certificate.NotBefore.UTC().Format(time.RFC3339),
+			"certExpires":       certificate.NotAfter.UTC().Format(time.RFC3339),
+			"certDaysRemaining": certificate.daysRemaining(now),
+			"certChainValid":    certificate.ChainError == "",
+			"certChainError":    certificate.ChainError,
+		}
+
+		if record, exists := records[certificate.Name]; exists {
+			// Update existing entry
+			err = env.update(ctx, collection, record.ID, payload)
+		} else {
+			// Create new entry, as upsert so a queued write cannot add a duplicate
+			filter := pocketbase.Filter("server = {:server} && name = {:name}", map[string]interface{}{
+				"server": serverID,
+				"name":   certificate.Name,
+			})
+			err = env.upsert(ctx, collection, filter, payload)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to send domain %s: %v", certificate.Name, err))
+			continue
+		}
+
+		log.Printf("Domain %s successfully processed, certificate expires in %d days.", certificate.Name, certificate.daysRemaining(now))
+	}
+
+	return errors.Join(errs...)
+}
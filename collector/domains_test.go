@@ -0,0 +1,34 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGetCertbotCertificatesKeepsUnreadable(t *testing.T) {
+	liveDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(liveDir, "example.com"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(liveDir, "README"), []byte("certbot"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	live, err := getCertbotCertificates(liveDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"example.com"}; !reflect.DeepEqual(live.Names, want) {
+		t.Errorf("Names = %v, want %v", live.Names, want)
+	}
+	if len(live.Certificates) != 0 {
+		t.Errorf("got %d certificates without cert.pem, want 0", len(live.Certificates))
+	}
+
+	live, err = getCertbotCertificates(filepath.Join(liveDir, "missing"))
+	if err != nil || live != nil {
+		t.Errorf("getCertbotCertificates(missing) = %v, %v, want nil, nil", live, err)
+	}
+}
@@ -0,0 +1,242 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/config"
+)
+
+func init() {
+	Register(harddriveCollector{})
+}
+
+// harddriveCollector reports the usage of every mounted file system to the
+// harddrives collection.
+type harddriveCollector struct{}
+
+// mount is an entry of /proc/self/mountinfo.
+type mount struct {
+	Device     string
+	Mountpoint string
+	FSType     string
+
+	// DeviceID is the major:minor number, shared by bind mounts.
+	DeviceID string
+
+	// Root is the directory of the file system mounted at Mountpoint, it is
+	// not "/" for bind mounts of a subdirectory.
+	Root string
+}
+
+// filesystemStats is the capacity of a file system in bytes and inodes.
+// Free is the space available to unprivileged users, like df(1) reports.
+type filesystemStats struct {
+	Total, Used, Free       uint64
+	InodesTotal, InodesFree uint64
+}
+
+// diskUsage is the usage of a single mounted file system.
+type diskUsage struct {
+	mount
+	filesystemStats
+}
+
+// harddriveReport is the result of a harddrive run. Mountpoints lists every
+// reported mount, also those whose usage could not be read, so that they are
+// not marked gone.
+type harddriveReport struct {
+	Mountpoints []string
+	Usages      []diskUsage
+}
+
+func (harddriveCollector) Name() string { return "harddrive" }
+
+func (h harddriveCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	// Get all mounted file systems
+	mounts, err := getMounts()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mounts: %v", err)
+	}
+
+	mounts = filterMounts(mounts, env.Config.Collector(h.Name()))
+
+	// Iterate over each mount and get disk usage
+	var report harddriveReport
+	for _, m := range mounts {
+		report.Mountpoints = append(report.Mountpoints, m.Mountpoint)
+
+		stats, err := statFilesystem(m.Mountpoint)
+		if err != nil {
+			log.Printf("Error getting disk usage for %s: %v", m.Mountpoint, err)
+			continue
+		}
+
+		report.Usages = append(report.Usages, diskUsage{mount: m, filesystemStats: stats})
+	}
+
+	return report, nil
+}
+
+func (h harddriveCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	report := data.(harddriveReport)
+
+	var errs []error
+	for _, usage := range report.Usages {
+		// Send the usage data to PocketBase
+		err := sendDiskUsageToPocketBase(ctx, env, usage)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to send usage for %s: %v", usage.Mountpoint, err))
+			continue
+		}
+
+		log.Printf("Hard drive usage successfully reported for %s! Current usage: %.2f%%", usage.Mountpoint, usage.usagePercentage())
+	}
+
+	// Mark the file systems that are no longer mounted
+	deleteAfter := time.Duration(env.Config.Collector(h.Name()).DeleteGoneAfter)
+	if err := reconcile(ctx, env, "harddrives", "path", report.Mountpoints, deleteAfter); err != nil {
+		errs = append(errs, err)
+	}
+
+	return errors.Join(errs...)
+}
+
+// usagePercentage is the used share of the space, computed like df(1) from
+// the space available to unprivileged users.
+func (u diskUsage) usagePercentage() float64 {
+	return percentage(u.Used, u.Used+u.Free)
+}
+
+// sendDiskUsageToPocketBase sends the usage data to the PocketBase API
+func sendDiskUsageToPocketBase(ctx context.Context, env *Env, usage diskUsage) error {
+	collection := "harddrives"
+
+	// Create the payload with ID, mount and capacity
+	payload := map[string]interface{}{
+		"path":                 usage.Mountpoint,
+		"device":               usage.Device,
+		"fsType":               usage.FSType,
+		"total":                usage.Total,
+		"used":                 usage.Used,
+		"free":                 usage.Free,
+		"usagePercentage":      usage.usagePercentage(),
+		"inodesTotal":          usage.InodesTotal,
+		"inodesUsed":           usage.InodesTotal - usage.InodesFree,
+		"inodesFree":           usage.InodesFree,
+		"inodeUsagePercentage": percentage(usage.InodesTotal-usage.InodesFree, usage.InodesTotal),
+		"server":               env.ServerID,
+		"sampledAt":            env.sampledAt(),
+		"runId":                env.RunID,
+	}
+
+	err := env.create(ctx, collection, payload)
+	if err != nil {
+		return fmt.Errorf("failed to update status: %v", err)
+	}
+
+	return nil
+}
+
+// getMounts parses /proc/self/mountinfo. Each line looks like
+//
+//	36 35 98:0 / /mnt rw,noatime master:1 - ext4 /dev/sda1 rw,errors=remount-ro
+//
+// where a variable number of optional fields is terminated by "-".
+func getMounts() ([]mount, error) {
+	file, err := os.Open("/proc/self/mountinfo")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open /proc/self/mountinfo: %v", err)
+	}
+	defer file.Close()
+
+	var mounts []mount
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+
+		separator := -1
+		for i := 6; i < len(fields); i++ {
+			if fields[i] == "-" {
+				separator = i
+				break
+			}
+		}
+		if separator < 0 || separator+2 >= len(fields) {
+			continue // Skip malformed lines
+		}
+
+		mounts = append(mounts, mount{
+			DeviceID:   fields[2],
+			Root:       unescapeMountinfo(fields[3]),
+			Mountpoint: unescapeMountinfo(fields[4]),
+			FSType:     fields[separator+1],
+			Device:     unescapeMountinfo(fields[separator+2]),
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("error reading /proc/self/mountinfo: %v", err)
+	}
+
+	return mounts, nil
+}
+
+// unescapeMountinfo decodes the octal escapes (\040 for a space) the kernel
+// uses for whitespace and backslashes in mountinfo.
+func unescapeMountinfo(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+3 < len(s) {
+			if v, err := strconv.ParseUint(s[i+1:i+4], 8, 8); err == nil {
+				b.WriteByte(byte(v))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// filterMounts drops pseudo file systems and mountpoints excluded in
+// smc.json. A device mounted several times, e.g. by bind mounts, is reported
+// once: at the mount of its root, otherwise at its first mountpoint. Btrfs
+// subvolumes share a device but are mounted with their own root ("/@",
+// "/@home"), so the first one is reported.
+func filterMounts(mounts []mount, settings config.CollectorConfig) []mount {
+	excludedTypes := map[string]bool{}
+	for _, fsType := range settings.ExcludeFSTypes {
+		excludedTypes[fsType] = true
+	}
+
+	chosen := map[string]int{}
+	var filtered []mount
+	for _, m := range mounts {
+		if excludedTypes[m.FSType] || !settings.Matches(m.Mountpoint) {
+			continue
+		}
+
+		if i, seen := chosen[m.DeviceID]; seen {
+			if m.Root == "/" && filtered[i].Root != "/" {
+				filtered[i] = m
+			}
+			continue
+		}
+		chosen[m.DeviceID] = len(filtered)
+
+		filtered = append(filtered, m)
+	}
+	return filtered
+}
@@ -0,0 +1,184 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(loadCollector{})
+}
+
+// loadCollector reports load average, uptime and pressure stall information
+// to the load collection.
+type loadCollector struct{}
+
+// loadInfo is the data sent to the load collection.
+type loadInfo struct {
+	Load1, Load5, Load15 float64
+	Running, Processes   int
+	Cores                int
+	Uptime               time.Duration
+	BootTime             time.Time
+
+	// Rebooted is set when the boot time moved since the previous run.
+	Rebooted bool
+
+	Pressure map[string]*pressure
+}
+
+// loadState is persisted to detect reboots between runs.
+type loadState struct {
+	BootTime time.Time `json:"bootTime"`
+}
+
+// bootTimeTolerance absorbs clock adjustments, which move the boot time the
+// kernel reports, when comparing boot times.
+const bootTimeTolerance = time.Minute
+
+func (loadCollector) Name() string { return "load" }
+
+func (loadCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	info, err := getLoadAverage()
+	if err != nil {
+		return nil, err
+	}
+
+	info.Uptime, err = getUptime()
+	if err != nil {
+		return nil, err
+	}
+	info.BootTime, err = readBootTime()
+	if err != nil {
+		return nil, err
+	}
+	info.Cores = runtime.NumCPU()
+
+	// Compare with the boot time seen by the previous run
+	var previous loadState
+	found, err := env.State.Load("load", &previous)
+	if err != nil {
+		log.Printf("Ignoring previous boot time: %v", err)
+	}
+	if found {
+		drift := info.BootTime.Sub(previous.BootTime)
+		info.Rebooted = drift > bootTimeTolerance || drift < -bootTimeTolerance
+	}
+	if err := env.State.Save("load", loadState{BootTime: info.BootTime}); err != nil {
+		log.Printf("Error saving boot time: %v", err)
+	}
+
+	// Pressure is optional, older kernels do not have PSI
+	info.Pressure = map[string]*pressure{}
+	for _, resource := range []string{"cpu", "io", "memory"} {
+		p, err := readPressure(resource)
+		if err != nil {
+			log.Printf("Error reading %s pressure: %v", resource, err)
+			continue
+		}
+		if p != nil {
+			info.Pressure[resource] = p
+		}
+	}
+
+	return info, nil
+}
+
+func (loadCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	info := data.(loadInfo)
+
+	perCore := func(load float64) float64 {
+		return math.Round(load/float64(info.Cores)*100) / 100
+	}
+
+	payload := map[string]interface{}{
+		"load1":         info.Load1,
+		"load5":         info.Load5,
+		"load15":        info.Load15,
+		"load1PerCore":  perCore(info.Load1),
+		"load5PerCore":  perCore(info.Load5),
+		"load15PerCore": perCore(info.Load15),
+		"cores":         info.Cores,
+		"running":       info.Running,
+		"processes":     info.Processes,
+		"uptimeSeconds": int64(info.Uptime.Seconds()),
+		"bootTime":      info.BootTime.UTC().Format(time.RFC3339),
+		"rebooted":      info.Rebooted,
+		"pressure":      info.Pressure,
+		"server":        env.ServerID,
+		"sampledAt":     env.sampledAt(),
+		"runId":         env.RunID,
+	}
+
+	err := env.create(ctx, "load", payload)
+	if err != nil {
+		return fmt.Errorf("failed to send load: %v", err)
+	}
+
+	if info.Rebooted {
+		log.Printf("Server was rebooted at %s", info.BootTime.Format(time.RFC3339))
+	}
+	log.Printf("Load successfully reported! Load average: %.2f %.2f %.2f on %d cores", info.Load1, info.Load5, info.Load15, info.Cores)
+	return nil
+}
+
+// getLoadAverage parses /proc/loadavg, e.g. "0.52 0.58 0.59 2/1234 5678".
+func getLoadAverage() (loadInfo, error) {
+	data, err := os.ReadFile("/proc/loadavg")
+	if err != nil {
+		return loadInfo{}, fmt.Errorf("failed to read /proc/loadavg: %v", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) < 4 {
+		return loadInfo{}, fmt.Errorf("unexpected format in /proc/loadavg")
+	}
+
+	var info loadInfo
+	for i, load := range []*float64{&info.Load1, &info.Load5, &info.Load15} {
+		*load, err = strconv.ParseFloat(fields[i], 64)
+		if err != nil {
+			return loadInfo{}, fmt.Errorf("failed to parse load average: %v", err)
+		}
+	}
+
+	running, total, ok := strings.Cut(fields[3], "/")
+	if !ok {
+		return loadInfo{}, fmt.Errorf("unexpected process counts in /proc/loadavg")
+	}
+	if info.Running, err = strconv.Atoi(running); err != nil {
+		return loadInfo{}, fmt.Errorf("failed to parse running processes: %v", err)
+	}
+	if info.Processes, err = strconv.Atoi(total); err != nil {
+		return loadInfo{}, fmt.Errorf("failed to parse process count: %v", err)
+	}
+
+	return info, nil
+}
+
+// getUptime parses the first field of /proc/uptime.
+func getUptime() (time.Duration, error) {
+	data, err := os.ReadFile("/proc/uptime")
+	if err != nil {
+		return 0, fmt.Errorf("failed to read /proc/uptime: %v", err)
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0, fmt.Errorf("unexpected format in /proc/uptime")
+	}
+
+	seconds, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse uptime: %v", err)
+	}
+
+	return time.Duration(seconds * float64(time.Second)), nil
+}
@@ -0,0 +1,153 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+func init() {
+	Register(memoryCollector{})
+}
+
+// memoryCollector reports RAM and swap usage to the memory collection.
+type memoryCollector struct{}
+
+// memoryUsage holds the memory figures in bytes.
+type memoryUsage struct {
+	Total     uint64
+	Available uint64
+	Free      uint64
+	Buffers   uint64
+	Cached    uint64
+	Dirty     uint64
+	SwapTotal uint64
+	SwapFree  uint64
+	Pressure  *pressure
+}
+
+func (memoryCollector) Name() string { return "memory" }
+
+func (memoryCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	usage, err := getMemoryUsage()
+	if err != nil {
+		return nil, err
+	}
+
+	// Memory pressure is optional, older kernels do not have PSI
+	usage.Pressure, err = readPressure("memory")
+	if err != nil {
+		log.Printf("Error reading memory pressure: %v", err)
+	}
+
+	return usage, nil
+}
+
+func (memoryCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	usage := data.(memoryUsage)
+
+	used := usage.Total - usage.Available
+	payload := map[string]interface{}{
+		"total":           usage.Total,
+		"available":       usage.Available,
+		"used":            used,
+		"free":            usage.Free,
+		"buffers":         usage.Buffers,
+		"cached":          usage.Cached,
+		"dirty":           usage.Dirty,
+		"swapTotal":       usage.SwapTotal,
+		"swapUsed":        usage.SwapTotal - usage.SwapFree,
+		"usagePercentage": percentage(used, usage.Total),
+		"swapPercentage":  percentage(usage.SwapTotal-usage.SwapFree, usage.SwapTotal),
+		"pressure":        usage.Pressure,
+		"server":          env.ServerID,
+		"sampledAt":       env.sampledAt(),
+		"runId":           env.RunID,
+	}
+
+	err := env.create(ctx, "memory", payload)
+	if err != nil {
+		return fmt.Errorf("failed to send memory usage: %v", err)
+	}
+
+	log.Printf("Memory usage successfully reported! Current usage: %.2f%%", percentage(used, usage.Total))
+	return nil
+}
+
+// getMemoryUsage parses /proc/meminfo.
+func getMemoryUsage() (memoryUsage, error) {
+	file, err := os.Open("/proc/meminfo")
+	if err != nil {
+		return memoryUsage{}, fmt.Errorf("failed to open /proc/meminfo: %v", err)
+	}
+	defer file.Close()
+
+	// Values are reported in kB, e.g. "MemTotal:       16314480 kB"
+	values := map[string]uint64{}
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, rest, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) == 0 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(fields[0], 10, 64)
+		if err != nil {
+			return memoryUsage{}, fmt.Errorf("failed to parse %s in /proc/meminfo: %v", key, err)
+		}
+		if len(fields) > 1 && fields[1] == "kB" {
+			value *= 1024
+		}
+		values[key] = value
+	}
+
+	if err := scanner.Err(); err != nil {
+		return memoryUsage{}, fmt.Errorf("error reading /proc/meminfo: %v", err)
+	}
+
+	if _, ok := values["MemTotal"]; !ok {
+		return memoryUsage{}, fmt.Errorf("MemTotal not found in /proc/meminfo")
+	}
+
+	usage := memoryUsage{
+		Total:     values["MemTotal"],
+		Available: values["MemAvailable"],
+		Free:      values["MemFree"],
+		Buffers:   values["Buffers"],
+		Cached:    values["Cached"],
+		Dirty:     values["Dirty"],
+		SwapTotal: values["SwapTotal"],
+		SwapFree:  values["SwapFree"],
+	}
+
+	// Kernels before 3.14 have no MemAvailable, estimate it like free(1)
+	if _, ok := values["MemAvailable"]; !ok {
+		usage.Available = usage.Free + usage.Buffers + usage.Cached
+	}
+	if usage.Available > usage.Total {
+		usage.Available = usage.Total
+	}
+	if usage.SwapFree > usage.SwapTotal {
+		usage.SwapFree = usage.SwapTotal
+	}
+
+	return usage, nil
+}
+
+// percentage returns part of total in percent, rounded to two decimals.
+func percentage(part, total uint64) float64 {
+	if total == 0 {
+		return 0
+	}
+	return math.Round(float64(part)/float64(total)*10000) / 100
+}
@@ -0,0 +1,224 @@
+package collector
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/dns"
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+)
+
+func init() {
+	Register(nameserverCollector{})
+}
+
+// unknownProvider is stored when the nameservers cannot be looked up.
+const unknownProvider = "unknown"
+
+// nameserverCollector looks up the DNS provider of every domain of this
+// server and stores it in the nameserver field. The records of the domains
+// are checked against the public addresses of the server and the result is
+// stored in the dns_status field.
+type nameserverCollector struct{}
+
+type Domain struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Server      string   `json:"server"`
+	Nameserver  string   `json:"nameserver"`
+	Nameservers []string `json:"nameservers"`
+
+	// CertDomains are the domains of the certificate, see the domains
+	// collector.
+	CertDomains []string `json:"certDomains"`
+
+	// DNS is the result of comparing the records with this server.
+	DNS *domainDNS `json:"-"`
+
+	// Registration is the RDAP registration data of the domain.
+	Registration *registration `json:"-"`
+}
+
+// names returns the domains of the certificate of the record. Records
+// without certificate domains, e.g. created before they were reported, use
+// their name, which is the certificate name and not always a domain.
+// Wildcards are skipped, they cannot be resolved or sent as SNI.
+func (d Domain) names() []string {
+	names := d.CertDomains
+	if len(names) == 0 {
+		names = []string{d.Name}
+	}
+
+	var domains []string
+	for _, name := range names {
+		if strings.Contains(name, "*") {
+			continue
+		}
+		domains = append(domains, name)
+	}
+	return domains
+}
+
+// lookupName returns the domain the nameservers and the registration of the
+// record are looked up for: the first domain of its certificate.
+func (d Domain) lookupName() string {
+	if len(d.CertDomains) > 0 {
+		return strings.TrimPrefix(d.CertDomains[0], "*.")
+	}
+	return d.Name
+}
+
+func (nameserverCollector) Name() string { return "nameserver" }
+
+func (nameserverCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	// Get domains from PocketBase
+	domains, err := getPocketBaseRecords(ctx, env.Client, env.ServerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve domains: %v", err)
+	}
+
+	resolver := dns.NewClient(env.Config.DNS.Resolver)
+
+	rules, err := loadProviderRules(env.Config.DNS)
+	if err != nil {
+		return nil, err
+	}
+
+	own, err := publicAddresses(env.Config.DNS)
+	if err != nil {
+		return nil, err
+	}
+
+	registrations := newRegistrationLookup(env)
+	defer registrations.save()
+
+	// Loop through domains and look up the nameservers
+	for i := range domains {
+		log.Printf("Processing domain: %s", domains[i].Name)
+
+		name := domains[i].lookupName()
+		provider, nameservers, err := getNameserverFromDNS(ctx, resolver, name, rules)
+		if err != nil {
+			log.Printf("Error getting nameserver for domain %s: %v", name, err)
+		}
+		domains[i].Nameserver = provider
+		domains[i].Nameservers = nameservers
+
+		if names := domains[i].names(); len(names) > 0 {
+			check := checkDomainDNS(ctx, resolver, names, own)
+			if check.Status != dnsStatusOK {
+				for _, name := range names {
+					if records := check.Records[name]; len(records.Foreign) > 0 || records.Error != "" {
+						log.Printf("DNS of domain %s: %s %v %s", name, check.Status, records.Foreign, records.Error)
+					}
+				}
+			}
+			domains[i].DNS = &check
+		}
+
+		registration := registrations.lookup(ctx, name)
+		domains[i].Registration = &registration
+	}
+
+	return domains, nil
+}
+
+func (nameserverCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	domains := data.([]Domain)
+	if len(domains) == 0 {
+		log.Println("No domains of this server found in PocketBase.")
+		return nil
+	}
+
+	// Update the domains with the nameserver in PocketBase
+	for _, domain := range domains {
+		err := updateDomainNameserver(ctx, env.Client, domain)
+		if err != nil {
+			log.Printf("Error updating domain %s: %v", domain.Name, err)
+			continue
+		}
+	}
+
+	log.Println("Completed processing all domains.")
+	return nil
+}
+
+// domainFields are the fields of the domains collection the collectors read.
+const domainFields = "id,name,server,certDomains"
+
+// getPocketBaseRecords returns every domain of this server that is not marked
+// gone, following all pages of the list. Each server only processes its own domains, so the
+// work is spread across the servers.
+func getPocketBaseRecords(ctx context.Context, client *pocketbase.Client, serverID string) ([]Domain, error) {
+	domains, err := pocketbase.ListAll[Domain](ctx, client, "domains", pocketbase.ListOptions{
+		Filter: pocketbase.Filter("server = {:server} && gone != true", map[string]interface{}{"server": serverID}),
+		Sort:   "name",
+		Fields: domainFields,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve records: %v", err)
+	}
+
+	return domains, nil
+}
+
+func updateDomainNameserver(ctx context.Context, client *pocketbase.Client, domain Domain) error {
+	payload := map[string]interface{}{
+		"nameserver":  domain.Nameserver,
+		"nameservers": domain.Nameservers,
+	}
+	if domain.DNS != nil {
+		payload["dns_status"] = domain.DNS.Status
+		payload["dns_records"] = domain.DNS.Records
+		payload["dns_checked"] = domain.DNS.Checked.Format(time.RFC3339)
+	}
+	if domain.Registration != nil {
+		for key, value := range registrationPayload(*domain.Registration) {
+			payload[key] = value
+		}
+	}
+
+	err := client.Update(ctx, "domains", domain.ID, payload, nil)
+	if err != nil {
+		return fmt.Errorf("failed to update domain %s: %v", domain.ID, err)
+	}
+
+	log.Printf("Domain %s updated with nameserver %s", domain.ID, domain.Nameserver)
+	return nil
+}
+
+// getNameserverFromDNS looks up the zone of domain and its NS records and
+// maps the nameserver hostnames to DNS providers. When no NS hostname is
+// recognised the primary nameserver of the SOA record is tried. It returns
+// the comma separated providers and the NS hostnames. Without a matching
+// rule the NS hostnames themselves are returned as the provider, so new
+// providers show up in the admin panel before a rule exists for them.
+func getNameserverFromDNS(ctx context.Context, resolver *dns.Client, domain string, rules providerRules) (string, []string, error) {
+	zone, err := resolver.LookupZone(ctx, domain)
+	if err != nil {
+		return unknownProvider, nil, err
+	}
+
+	nameservers, err := resolver.LookupNS(ctx, zone.Name)
+	if err != nil {
+		return unknownProvider, nil, err
+	}
+	if len(nameservers) == 0 {
+		return unknownProvider, nil, fmt.Errorf("no nameservers found for domain %s", domain)
+	}
+	sort.Strings(nameservers)
+
+	names := rules.providers(nameservers)
+	if len(names) == 0 {
+		names = rules.providers([]string{zone.SOA.MName})
+	}
+
+	if len(names) == 0 {
+		return strings.Join(nameservers, ", "), nameservers, nil
+	}
+	return strings.Join(names, ", "), nameservers, nil
+}
@@ -0,0 +1,63 @@
+package collector
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDomainNames(t *testing.T) {
+	tests := []struct {
+		domain     Domain
+		names      string
+		lookupName string
+	}{
+		{
+			Domain{Name: "example.com-0001", CertDomains: []string{"example.com", "*.example.com", "www.example.com"}},
+			"example.com,www.example.com",
+			"example.com",
+		},
+		{
+			Domain{Name: "shop", CertDomains: []string{"*.example.net"}},
+			"",
+			"example.net",
+		},
+		{
+			// Created before the certificate domains were reported
+			Domain{Name: "example.org"},
+			"example.org",
+			"example.org",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := strings.Join(tt.domain.names(), ","); got != tt.names {
+			t.Errorf("names of %s = %q, want %q", tt.domain.Name, got, tt.names)
+		}
+		if got := tt.domain.lookupName(); got != tt.lookupName {
+			t.Errorf("lookupName of %s = %q, want %q", tt.domain.Name, got, tt.lookupName)
+		}
+	}
+}
+
+func TestCombineDNSStatus(t *testing.T) {
+	tests := []struct {
+		statuses []string
+		want     string
+	}{
+		{[]string{dnsStatusOK, dnsStatusOK}, dnsStatusOK},
+		{[]string{dnsStatusOK, dnsStatusDrift}, dnsStatusPartial},
+		{[]string{dnsStatusOK, dnsStatusUnresolved}, dnsStatusPartial},
+		{[]string{dnsStatusDrift, dnsStatusDrift}, dnsStatusDrift},
+		{[]string{dnsStatusDrift, dnsStatusUnresolved}, dnsStatusDrift},
+		{[]string{dnsStatusPartial, dnsStatusDrift}, dnsStatusPartial},
+		{[]string{dnsStatusUnresolved, dnsStatusUnresolved}, dnsStatusUnresolved},
+		{[]string{dnsStatusUnknown, dnsStatusUnknown}, dnsStatusUnknown},
+		{[]string{dnsStatusOK, dnsStatusError}, dnsStatusError},
+	}
+
+	for _, tt := range tests {
+		if got := combineDNSStatus(tt.statuses); got != tt.want {
+			t.Errorf("combineDNSStatus(%v) = %q, want %q", tt.statuses, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register(networkCollector{})
+}
+
+// networkCollector reports traffic and error counters of every network
+// interface to the network collection, one record per interface.
+type networkCollector struct{}
+
+// interfaceCounters are the /proc/net/dev counters of one interface.
+type interfaceCounters struct {
+	RxBytes   uint64 `json:"rxBytes"`
+	RxPackets uint64 `json:"rxPackets"`
+	RxErrors  uint64 `json:"rxErrors"`
+	RxDropped uint64 `json:"rxDropped"`
+	TxBytes   uint64 `json:"txBytes"`
+	TxPackets uint64 `json:"txPackets"`
+	TxErrors  uint64 `json:"txErrors"`
+	TxDropped uint64 `json:"txDropped"`
+}
+
+// networkSample is one read of /proc/net/dev, persisted between runs.
+type networkSample struct {
+	Time       time.Time                    `json:"time"`
+	Interfaces map[string]interfaceCounters `json:"interfaces"`
+}
+
+// interfaceUsage is the counters of an interface and, when a previous
+// sample exists, the rates per second since then.
+type interfaceUsage struct {
+	Name     string
+	Counters interfaceCounters
+	Rates    map[string]float64
+	Period   time.Duration
+}
+
+func (networkCollector) Name() string { return "network" }
+
+func (n networkCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	current, err := readNetworkSample()
+	if err != nil {
+		return nil, err
+	}
+
+	var previous networkSample
+	found, err := env.State.Load("network", &previous)
+	if err != nil {
+		log.Printf("Ignoring previous network sample: %v", err)
+	}
+
+	if err := env.State.Save("network", current); err != nil {
+		log.Printf("Error saving network sample: %v", err)
+	}
+
+	settings := env.Config.Collector(n.Name())
+	period := current.Time.Sub(previous.Time)
+
+	var usages []interfaceUsage
+	for name, counters := range current.Interfaces {
+		if !settings.Matches(name) {
+			continue
+		}
+
+		usage := interfaceUsage{Name: name, Counters: counters}
+		if prev, ok := previous.Interfaces[name]; found && ok && period > 0 {
+			usage.Rates = networkRates(prev, counters, period)
+			usage.Period = period
+		}
+		usages = append(usages, usage)
+	}
+
+	sort.Slice(usages, func(i, j int) bool { return usages[i].Name < usages[j].Name })
+	return usages, nil
+}
+
+func (networkCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	var errs []error
+	for _, usage := range data.([]interfaceUsage) {
+		c := usage.Counters
+		payload := map[string]interface{}{
+			"interface": usage.Name,
+			"rxBytes":   c.RxBytes,
+			"rxPackets": c.RxPackets,
+			"rxErrors":  c.RxErrors,
+			"rxDropped": c.RxDropped,
+			"txBytes":   c.TxBytes,
+			"txPackets": c.TxPackets,
+			"txErrors":  c.TxErrors,
+			"txDropped": c.TxDropped,
+			"server":    env.ServerID,
+			"sampledAt": env.sampledAt(),
+			"runId":     env.RunID,
+		}
+		for key, rate := range usage.Rates {
+			payload[key] = rate
+		}
+		if usage.Period > 0 {
+			payload["periodSeconds"] = math.Round(usage.Period.Seconds())
+		}
+
+		err := env.create(ctx, "network", payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to send network usage for %s: %v", usage.Name, err))
+			continue
+		}
+
+		log.Printf("Network usage successfully reported for %s!", usage.Name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// readNetworkSample parses /proc/net/dev. The first two lines are headers,
+// every other line looks like
+//
+//	eth0: 1632 24 0 0 0 0 0 0 2106 24 0 0 0 0 0 0
+func readNetworkSample() (networkSample, error) {
+	data, err := os.ReadFile("/proc/net/dev")
+	if err != nil {
+		return networkSample{}, fmt.Errorf("failed to read /proc/net/dev: %v", err)
+	}
+
+	sample := networkSample{
+		Time:       time.Now(),
+		Interfaces: map[string]interfaceCounters{},
+	}
+
+	lines := strings.Split(string(data), "\n")
+	for _, line := range lines {
+		name, rest, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+
+		fields := strings.Fields(rest)
+		if len(fields) < 16 {
+			continue
+		}
+
+		var values [16]uint64
+		for i := range values {
+			values[i], err = strconv.ParseUint(fields[i], 10, 64)
+			if err != nil {
+				return networkSample{}, fmt.Errorf("failed to parse counters of %s: %v", name, err)
+			}
+		}
+
+		sample.Interfaces[strings.TrimSpace(name)] = interfaceCounters{
+			RxBytes:   values[0],
+			RxPackets: values[1],
+			RxErrors:  values[2],
+			RxDropped: values[3],
+			TxBytes:   values[8],
+			TxPackets: values[9],
+			TxErrors:  values[10],
+			TxDropped: values[11],
+		}
+	}
+
+	return sample, nil
+}
+
+// networkRates computes per second rates between two samples. Rates are
+// omitted when the counters went backwards (reboot or driver reload).
+func networkRates(previous, current interfaceCounters, period time.Duration) map[string]float64 {
+	if current.RxBytes < previous.RxBytes || current.TxBytes < previous.TxBytes ||
+		current.RxPackets < previous.RxPackets || current.TxPackets < previous.TxPackets {
+		return nil
+	}
+
+	rate := func(prev, cur uint64) float64 {
+		if cur < prev {
+			return 0
+		}
+		return math.Round(float64(cur-prev)/period.Seconds()*100) / 100
+	}
+
+	return map[string]float64{
+		"rxBytesPerSecond":   rate(previous.RxBytes, current.RxBytes),
+		"txBytesPerSecond":   rate(previous.TxBytes, current.TxBytes),
+		"rxPacketsPerSecond": rate(previous.RxPackets, current.RxPackets),
+		"txPacketsPerSecond": rate(previous.TxPackets, current.TxPackets),
+		"rxErrorsPerSecond":  rate(previous.RxErrors, current.RxErrors),
+		"txErrorsPerSecond":  rate(previous.TxErrors, current.TxErrors),
+		"rxDroppedPerSecond": rate(previous.RxDropped, current.RxDropped),
+		"txDroppedPerSecond": rate(previous.TxDropped, current.TxDropped),
+	}
+}
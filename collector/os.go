@@ -0,0 +1,471 @@
+package collector
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+)
+
+func init() {
+	Register(osCollector{})
+}
+
+// osCollector reports the operating system and hardware of this server to
+// the server_os collection.
+type osCollector struct{}
+
+// Struct to represent the payload for PocketBase server_os collection
+type ServerOS struct {
+	Server string `json:"server"`
+
+	// Name is the operating system as known to Go, e.g. "linux".
+	Name string `json:"name"`
+
+	// Distribution, Version and PrettyName are ID, VERSION_ID and
+	// PRETTY_NAME of /etc/os-release, e.g. "debian", "12" and
+	// "Debian GNU/Linux 12 (bookworm)".
+	Distribution string `json:"distribution"`
+	Version      string `json:"version"`
+	PrettyName   string `json:"prettyName"`
+
+	// LSBRelease repeats PrettyName for dashboards that read the field
+	// lsb_release used to fill.
+	LSBRelease string `json:"lsb_release"`
+
+	Arch   string `json:"arch"`
+	Kernel string `json:"kernel"`
+
+	CPUModel    string `json:"cpuModel"`
+	CPUCores    int    `json:"cpuCores"`
+	CPUThreads  int    `json:"cpuThreads"`
+	MemoryTotal uint64 `json:"memoryTotal"`
+
+	// Virtualization is the hypervisor ("kvm", "vmware", ...) and Container
+	// the container runtime ("docker", "lxc", ...), "none" when there is
+	// none, like systemd-detect-virt reports them.
+	Virtualization string `json:"virtualization"`
+	Container      string `json:"container"`
+
+	BootTime  string `json:"bootTime"`
+	MachineID string `json:"machineId"`
+	Golang    string `json:"golang"`
+	Host      string `json:"host"`
+}
+
+func (osCollector) Name() string { return "os" }
+
+func (osCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	return getServerOSInfo()
+}
+
+func (osCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	return sendServerOSToPocketBase(ctx, env, data.(ServerOS))
+}
+
+// getServerOSInfo gathers the operating system and hardware information.
+// Only the hostname is required, everything else is left empty when it
+// cannot be read, e.g. on minimal images or other operating systems.
+func getServerOSInfo() (ServerOS, error) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		return ServerOS{}, fmt.Errorf("failed to get hostname: %v", err)
+	}
+
+	info := ServerOS{
+		Name:   runtime.GOOS,
+		Arch:   runtime.GOARCH,
+		Golang: runtime.Version(),
+		Host:   hostname,
+	}
+
+	// The machine name of the kernel, the binary may run emulated
+	if release, machine, err := uname(); err == nil {
+		info.Kernel = release
+		info.Arch = machine
+	}
+
+	release, err := readOSRelease()
+	if err != nil {
+		log.Printf("Error reading os-release: %v", err)
+	}
+	info.Distribution = release["ID"]
+	info.Version = release["VERSION_ID"]
+	info.PrettyName = release["PRETTY_NAME"]
+	if info.PrettyName == "" {
+		info.PrettyName = release["NAME"]
+	}
+	info.LSBRelease = info.PrettyName
+
+	if runtime.GOOS != "linux" {
+		return info, nil
+	}
+
+	cpu, err := readCPUInfo()
+	if err != nil {
+		log.Printf("Error reading CPU info: %v", err)
+	}
+	info.CPUModel = cpu.Model
+	info.CPUCores = cpu.Cores
+	info.CPUThreads = cpu.Threads
+
+	if memory, err := getMemoryUsage(); err == nil {
+		info.MemoryTotal = memory.Total
+	} else {
+		log.Printf("Error reading total memory: %v", err)
+	}
+
+	if bootTime, err := readBootTime(); err == nil {
+		info.BootTime = bootTime.UTC().Format(time.RFC3339)
+	} else {
+		log.Printf("Error reading boot time: %v", err)
+	}
+
+	info.Virtualization = detectVirtualization()
+	info.Container = detectContainer()
+	info.MachineID = readMachineID()
+
+	return info, nil
+}
+
+// readOSRelease parses os-release(5), a list of shell style KEY=value
+// assignments.
+func readOSRelease() (map[string]string, error) {
+	values := map[string]string{}
+
+	file, err := os.Open("/etc/os-release")
+	if errors.Is(err, os.ErrNotExist) {
+		file, err = os.Open("/usr/lib/os-release")
+	}
+	if err != nil {
+		return values, err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		switch {
+		case len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"':
+			if unquoted, err := strconv.Unquote(value); err == nil {
+				value = unquoted
+			} else {
+				value = value[1 : len(value)-1]
+			}
+		case len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'':
+			value = value[1 : len(value)-1]
+		}
+		values[key] = value
+	}
+	return values, scanner.Err()
+}
+
+// cpuInfo is the processor summary of /proc/cpuinfo.
+type cpuInfo struct {
+	Model   string
+	Cores   int
+	Threads int
+}
+
+// readCPUInfo parses /proc/cpuinfo. Threads counts the logical processors
+// and Cores the distinct physical cores, where the kernel reports them.
+func readCPUInfo() (cpuInfo, error) {
+	file, err := os.Open("/proc/cpuinfo")
+	if err != nil {
+		return cpuInfo{}, err
+	}
+	defer file.Close()
+
+	var info cpuInfo
+	var physicalID string
+	cores := map[string]bool{}
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), ":")
+		if !ok {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "processor":
+			info.Threads++
+		case "physical id":
+			physicalID = value
+		case "core id":
+			cores[physicalID+"/"+value] = true
+		case "model name", "cpu model", "uarch", "Model", "Hardware", "cpu":
+			// x86, MIPS, RISC-V, Raspberry Pi, older ARM and POWER name the
+			// model differently, the first one found wins
+			if info.Model == "" {
+				info.Model = value
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return info, err
+	}
+
+	info.Cores = len(cores)
+	if info.Cores == 0 {
+		info.Cores = info.Threads
+	}
+	return info, nil
+}
+
+// readMachineID returns the machine ID of systemd or D-Bus, empty when the
+// server has none.
+func readMachineID() string {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		if id := readTrimmed(path); id != "" {
+			return id
+		}
+	}
+	return ""
+}
+
+// dmiVendors maps the prefix of DMI vendor and product names to the
+// hypervisor names systemd-detect-virt uses.
+var dmiVendors = []struct{ prefix, name string }{
+	{"KVM", "kvm"},
+	{"OpenStack", "kvm"},
+	{"KubeVirt", "kvm"},
+	{"Amazon EC2", "amazon"},
+	{"QEMU", "qemu"},
+	{"VMware", "vmware"},
+	{"VMW", "vmware"},
+	{"innotek GmbH", "oracle"},
+	{"VirtualBox", "oracle"},
+	{"Oracle Corporation", "oracle"},
+	{"Xen", "xen"},
+	{"Bochs", "bochs"},
+	{"Parallels", "parallels"},
+	{"BHYVE", "bhyve"},
+	{"Hyper-V", "microsoft"},
+	{"Apple Virtualization", "apple"},
+	{"Google Compute Engine", "google"},
+}
+
+// detectVirtualization returns the hypervisor this server runs on, "vm" for
+// an unknown hypervisor, or "none" on bare metal.
+func detectVirtualization() string {
+	// Hyper-V and Azure only name the product
+	if readTrimmed("/sys/class/dmi/id/sys_vendor") == "Microsoft Corporation" &&
+		readTrimmed("/sys/class/dmi/id/product_name") == "Virtual Machine" {
+		return "microsoft"
+	}
+
+	for _, file := range []string{"sys_vendor", "product_name", "board_vendor", "bios_vendor"} {
+		value := readTrimmed("/sys/class/dmi/id/" + file)
+		for _, vendor := range dmiVendors {
+			if strings.HasPrefix(value, vendor.prefix) {
+				return vendor.name
+			}
+		}
+	}
+
+	// Xen guests without DMI, the control domain itself is not virtualized
+	if _, err := os.Stat("/proc/xen"); err == nil {
+		if !strings.Contains(readTrimmed("/proc/xen/capabilities"), "control_d") {
+			return "xen"
+		}
+	}
+	if hypervisor := readTrimmed("/sys/hypervisor/type"); hypervisor != "" {
+		return hypervisor
+	}
+
+	// The CPU reports a hypervisor without identifying it
+	if data, err := os.ReadFile("/proc/cpuinfo"); err == nil {
+		for _, line := range strings.Split(string(data), "\n") {
+			key, value, ok := strings.Cut(line, ":")
+			if ok && strings.TrimSpace(key) == "flags" {
+				for _, flag := range strings.Fields(value) {
+					if flag == "hypervisor" {
+						return "vm"
+					}
+				}
+				break
+			}
+		}
+	}
+
+	return "none"
+}
+
+// detectContainer returns the container runtime this process runs in, or
+// "none".
+func detectContainer() string {
+	if _, err := os.Stat("/.dockerenv"); err == nil {
+		return "docker"
+	}
+	if _, err := os.Stat("/run/.containerenv"); err == nil {
+		return "podman"
+	}
+
+	// Set by systemd-nspawn, LXC and others
+	if container := readTrimmed("/run/systemd/container"); container != "" {
+		return container
+	}
+	if data, err := os.ReadFile("/proc/1/environ"); err == nil {
+		for _, variable := range strings.Split(string(data), "\x00") {
+			if container, ok := strings.CutPrefix(variable, "container="); ok && container != "" {
+				return container
+			}
+		}
+	}
+
+	if release := readTrimmed("/proc/sys/kernel/osrelease"); strings.Contains(strings.ToLower(release), "microsoft") {
+		return "wsl"
+	}
+	if _, err := os.Stat("/proc/vz"); err == nil {
+		if _, err := os.Stat("/proc/bc"); err != nil {
+			return "openvz"
+		}
+	}
+
+	cgroup, _ := os.ReadFile("/proc/1/cgroup")
+	switch {
+	case strings.Contains(string(cgroup), "kubepods"):
+		return "kubernetes"
+	case strings.Contains(string(cgroup), "docker"):
+		return "docker"
+	case strings.Contains(string(cgroup), "lxc"):
+		return "lxc"
+	}
+
+	return "none"
+}
+
+// readTrimmed returns the content of path without surrounding whitespace,
+// empty when it cannot be read.
+func readTrimmed(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// serverOSRecord is a record of the server_os collection.
+type serverOSRecord struct {
+	ID string `json:"id"`
+	ServerOS
+}
+
+// osResendInterval is how often the server_os record is sent even though
+// nothing changed, so a record edited or deleted in PocketBase is restored.
+const osResendInterval = 24 * time.Hour
+
+// sentServerOS is the state of the last server_os record sent. PocketBase
+// normalises some fields, e.g. dates, so changes are detected by comparing
+// hashes of the payloads sent instead of the stored record.
+type sentServerOS struct {
+	Hash string    `json:"hash"`
+	Sent time.Time `json:"sent"`
+}
+
+// sendServerOSToPocketBase keeps one server_os record per server. The
+// record is created on the first run and afterwards only updated when
+// something changed. Upgrades of the distribution or kernel are added to the
+// history collection when one is configured.
+func sendServerOSToPocketBase(ctx context.Context, env *Env, serverOS ServerOS) error {
+	collection := "server_os"
+
+	// Add the server ID to the payload
+	serverOS.Server = env.ServerID
+
+	body, err := json.Marshal(serverOS)
+	if err != nil {
+		return fmt.Errorf("failed to encode server OS info: %v", err)
+	}
+	sum := sha256.Sum256(body)
+	current := sentServerOS{Hash: hex.EncodeToString(sum[:]), Sent: time.Now().UTC()}
+
+	var previous sentServerOS
+	if _, err := env.State.Load("os", &previous); err != nil {
+		log.Printf("Ignoring previously sent server OS info: %v", err)
+	}
+	if previous.Hash == current.Hash && time.Since(previous.Sent) < osResendInterval {
+		log.Println("Server OS info unchanged.")
+		return nil
+	}
+
+	if err := updateServerOS(ctx, env, collection, serverOS); err != nil {
+		return err
+	}
+	if err := env.State.Save("os", current); err != nil {
+		log.Printf("Error saving sent server OS info: %v", err)
+	}
+	return nil
+}
+
+// updateServerOS updates the server_os record of this server, or creates it.
+func updateServerOS(ctx context.Context, env *Env, collection string, serverOS ServerOS) error {
+	// Older versions added a record every run, the newest one is kept up to date
+	filter := pocketbase.Filter("server = {:server}", map[string]interface{}{"server": env.ServerID})
+	result, err := pocketbase.List[serverOSRecord](ctx, env.Client, collection, pocketbase.ListOptions{
+		PerPage:   1,
+		Filter:    filter,
+		Sort:      "-created",
+		SkipTotal: true,
+	})
+
+	// Without the existing record an upgrade cannot be detected, so an
+	// unreachable PocketBase gets a queued upsert of the current info
+	if err != nil || len(result.Items) == 0 {
+		if err != nil && !pocketbase.IsRetryable(err) {
+			return fmt.Errorf("failed to retrieve server OS info: %v", err)
+		}
+		if err := env.upsert(ctx, collection, filter, serverOS); err != nil {
+			return fmt.Errorf("failed to insert server OS info: %v", err)
+		}
+		log.Println("Server OS info successfully sent to PocketBase.")
+		return nil
+	}
+
+	existing := result.Items[0]
+	if err := env.update(ctx, collection, existing.ID, serverOS); err != nil {
+		return fmt.Errorf("failed to update server OS info: %v", err)
+	}
+	log.Println("Server OS info successfully updated in PocketBase.")
+
+	upgraded := existing.Distribution != serverOS.Distribution ||
+		existing.Version != serverOS.Version ||
+		existing.Kernel != serverOS.Kernel
+	if history := env.Config.OS.History; history != "" && upgraded {
+		payload := map[string]interface{}{
+			"server":               env.ServerID,
+			"previousDistribution": existing.Distribution,
+			"previousVersion":      existing.Version,
+			"previousPrettyName":   existing.PrettyName,
+			"previousKernel":       existing.Kernel,
+			"distribution":         serverOS.Distribution,
+			"version":              serverOS.Version,
+			"prettyName":           serverOS.PrettyName,
+			"kernel":               serverOS.Kernel,
+		}
+		if err := env.create(ctx, history, payload); err != nil {
+			return fmt.Errorf("failed to record OS upgrade: %v", err)
+		}
+		log.Printf("OS upgrade recorded: %s (%s) -> %s (%s)", existing.PrettyName, existing.Kernel, serverOS.PrettyName, serverOS.Kernel)
+	}
+
+	return nil
+}
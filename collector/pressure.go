@@ -0,0 +1,88 @@
+package collector
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// pressureStall is one line of a /proc/pressure file: the share of time
+// tasks were stalled over the last 10, 60 and 300 seconds, and the total
+// stall time in microseconds.
+type pressureStall struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// pressure is the pressure stall information (PSI) of one resource. Full is
+// nil for resources the kernel does not report it for (cpu before 5.13).
+type pressure struct {
+	Some *pressureStall `json:"some"`
+	Full *pressureStall `json:"full,omitempty"`
+}
+
+// readPressure reads /proc/pressure/<resource>. It returns nil without an
+// error when the kernel has no PSI support or it was disabled with psi=0.
+func readPressure(resource string) (*pressure, error) {
+	data, err := os.ReadFile("/proc/pressure/" + resource)
+	if errors.Is(err, os.ErrNotExist) || errors.Is(err, syscall.EOPNOTSUPP) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read /proc/pressure/%s: %v", resource, err)
+	}
+
+	var p pressure
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		stall, err := parsePressureStall(fields[1:])
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse /proc/pressure/%s: %v", resource, err)
+		}
+
+		switch fields[0] {
+		case "some":
+			p.Some = stall
+		case "full":
+			p.Full = stall
+		}
+	}
+
+	return &p, nil
+}
+
+// parsePressureStall parses the key=value pairs of a PSI line.
+func parsePressureStall(fields []string) (*pressureStall, error) {
+	var stall pressureStall
+	for _, field := range fields {
+		key, value, ok := strings.Cut(field, "=")
+		if !ok {
+			return nil, fmt.Errorf("unexpected field %q", field)
+		}
+
+		var err error
+		switch key {
+		case "avg10":
+			stall.Avg10, err = strconv.ParseFloat(value, 64)
+		case "avg60":
+			stall.Avg60, err = strconv.ParseFloat(value, 64)
+		case "avg300":
+			stall.Avg300, err = strconv.ParseFloat(value, 64)
+		case "total":
+			stall.Total, err = strconv.ParseUint(value, 10, 64)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", key, err)
+		}
+	}
+	return &stall, nil
+}
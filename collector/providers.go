@@ -0,0 +1,165 @@
+package collector
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/Server-Manager-cloud/cronjobs/config"
+)
+
+// providerRule maps nameserver hostnames to a DNS provider. Exactly one of
+// Suffix, Glob and Regex is set. When several rules match a hostname the one
+// with the highest Priority wins, ties go to the rule listed first.
+type providerRule struct {
+	Provider string `json:"provider"`
+	Suffix   string `json:"suffix,omitempty"`
+	Glob     string `json:"glob,omitempty"`
+	Regex    string `json:"regex,omitempty"`
+	Priority int    `json:"priority,omitempty"`
+
+	re *regexp.Regexp
+}
+
+// defaultProviderRules are the rules used unless dns.providerRules names a
+// rules file. They are built into the binary so provider detection does not
+// depend on the working directory.
+//
+//go:embed providers.json
+var defaultProviderRules []byte
+
+// providerRulesFile is the format of the file dns.providerRules points to.
+type providerRulesFile struct {
+	Rules []providerRule `json:"rules"`
+}
+
+// providerRules is a list of rules ordered by descending priority.
+type providerRules []providerRule
+
+// loadProviderRules reads the rules file, or the built-in rules when none is
+// set, and appends the inline providers of smc.json as suffix or glob rules
+// with priority 0. The file is read on every run so rule changes apply
+// without restarting the daemon.
+func loadProviderRules(cfg config.DNSConfig) (providerRules, error) {
+	data, source := defaultProviderRules, "built-in provider rules"
+	if cfg.ProviderRules != "" {
+		var err error
+		data, err = os.ReadFile(cfg.ProviderRules)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read provider rules: %v", err)
+		}
+		source = "provider rules " + cfg.ProviderRules
+	}
+
+	var file providerRulesFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", source, err)
+	}
+	rules := providerRules(file.Rules)
+
+	// Sort the inline providers so they are appended in a stable order
+	names := make([]string, 0, len(cfg.Providers))
+	for name := range cfg.Providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		for _, pattern := range cfg.Providers[name] {
+			rule := providerRule{Provider: name, Suffix: pattern}
+			if strings.Contains(pattern, "*") {
+				rule = providerRule{Provider: name, Glob: pattern}
+			}
+			rules = append(rules, rule)
+		}
+	}
+
+	for i := range rules {
+		if err := rules[i].compile(); err != nil {
+			return nil, fmt.Errorf("invalid provider rule %d: %v", i+1, err)
+		}
+	}
+
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Priority > rules[j].Priority })
+	return rules, nil
+}
+
+// compile validates the rule and prepares its matcher.
+func (r *providerRule) compile() error {
+	if r.Provider == "" {
+		return fmt.Errorf("provider is missing")
+	}
+
+	set := 0
+	for _, matcher := range []string{r.Suffix, r.Glob, r.Regex} {
+		if matcher != "" {
+			set++
+		}
+	}
+	if set != 1 {
+		return fmt.Errorf("rule for %s needs exactly one of suffix, glob and regex", r.Provider)
+	}
+
+	r.Suffix = strings.ToLower(strings.Trim(r.Suffix, "."))
+	r.Glob = strings.ToLower(r.Glob)
+
+	if r.Glob != "" {
+		if _, err := path.Match(r.Glob, ""); err != nil {
+			return fmt.Errorf("invalid glob %q: %v", r.Glob, err)
+		}
+	}
+
+	if r.Regex != "" {
+		re, err := regexp.Compile("(?i)" + r.Regex)
+		if err != nil {
+			return fmt.Errorf("invalid regex %q: %v", r.Regex, err)
+		}
+		r.re = re
+	}
+	return nil
+}
+
+// matches reports whether the rule matches a lowercase hostname.
+func (r *providerRule) matches(host string) bool {
+	switch {
+	case r.re != nil:
+		return r.re.MatchString(host)
+	case r.Glob != "":
+		ok, _ := path.Match(r.Glob, host)
+		return ok
+	default:
+		return host == r.Suffix || strings.HasSuffix(host, "."+r.Suffix)
+	}
+}
+
+// match returns the provider of the nameserver host, or "" when no rule
+// matches.
+func (rules providerRules) match(host string) string {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	for i := range rules {
+		if rules[i].matches(host) {
+			return rules[i].Provider
+		}
+	}
+	return ""
+}
+
+// providers returns the distinct providers of the given nameserver hosts, in
+// host order.
+func (rules providerRules) providers(hosts []string) []string {
+	var names []string
+	seen := map[string]bool{}
+	for _, host := range hosts {
+		name := rules.match(host)
+		if name != "" && !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	return names
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Server-Manager-cloud/cronjobs/config"
+)
+
+func TestLoadProviderRulesBuiltIn(t *testing.T) {
+	rules, err := loadProviderRules(config.DNSConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"ada.ns.cloudflare.com.":    "cloudflare",
+		"ns-123.awsdns-45.co.uk":    "route53",
+		"helium.ns.hetzner.de":      "hetzner",
+		"ns1.unknown-provider.test": "",
+	}
+	for host, want := range tests {
+		if got := rules.match(host); got != want {
+			t.Errorf("match(%q) = %q, want %q", host, got, want)
+		}
+	}
+}
+
+func TestLoadProviderRulesFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "providers.json")
+	data := `{"rules": [{"provider": "own", "suffix": "example.net"}]}`
+	if err := os.WriteFile(file, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := loadProviderRules(config.DNSConfig{
+		ProviderRules: file,
+		Providers:     map[string][]string{"inline": {"ns*.example.org"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := rules.match("ns1.example.net"); got != "own" {
+		t.Errorf("match(ns1.example.net) = %q, want own", got)
+	}
+	if got := rules.match("ns2.example.org"); got != "inline" {
+		t.Errorf("match(ns2.example.org) = %q, want inline", got)
+	}
+	// The file replaces the built-in rules
+	if got := rules.match("ada.ns.cloudflare.com"); got != "" {
+		t.Errorf("match(ada.ns.cloudflare.com) = %q, want no built-in rules", got)
+	}
+}
+
+func TestLoadProviderRulesMissingFile(t *testing.T) {
+	_, err := loadProviderRules(config.DNSConfig{ProviderRules: filepath.Join(t.TempDir(), "missing.json")})
+	if err == nil {
+		t.Error("loading a missing rules file succeeded, want an error")
+	}
+}
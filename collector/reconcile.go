@@ -0,0 +1,147 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+)
+
+// reconcile compares the records of this server in collection with what a
+// collector observed, identified by keyField (e.g. the mount path). Records
+// whose key was not observed are marked gone with the time in goneSince,
+// and records observed again lose the mark. Records gone for longer than
+// deleteAfter are deleted, unless it is zero.
+func reconcile(ctx context.Context, env *Env, collection, keyField string, observed []string, deleteAfter time.Duration) error {
+	now := time.Now().UTC()
+	params := map[string]interface{}{
+		"server": env.ServerID,
+		"cutoff": now.Add(-deleteAfter),
+	}
+
+	// Filters on the key are built from one placeholder per observed key
+	var matches, differs []string
+	for i, key := range observed {
+		name := fmt.Sprintf("key%d", i)
+		params[name] = key
+		matches = append(matches, fmt.Sprintf("%s = {:%s}", keyField, name))
+		differs = append(differs, fmt.Sprintf("%s != {:%s}", keyField, name))
+	}
+
+	var errs []error
+	update := func(filter string, payload map[string]interface{}, action string) {
+		if err := updateReconciled(ctx, env, collection, keyField, pocketbase.Filter(filter, params), payload, action); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	stale := "server = {:server} && gone != true"
+	if len(differs) > 0 {
+		stale += " && " + strings.Join(differs, " && ")
+	}
+	update(stale, map[string]interface{}{
+		"gone":      true,
+		"goneSince": now.Format(time.RFC3339),
+	}, "disappeared, marked as gone")
+
+	if len(matches) > 0 {
+		update("server = {:server} && gone = true && ("+strings.Join(matches, " || ")+")", map[string]interface{}{
+			"gone":      false,
+			"goneSince": "",
+		}, "is back, gone mark removed")
+	}
+
+	if deleteAfter > 0 {
+		update("server = {:server} && gone = true && goneSince < {:cutoff}", nil, "deleted after being gone for "+deleteAfter.String())
+	}
+
+	return errors.Join(errs...)
+}
+
+// updateReconciled updates the records of collection matching filter with
+// payload, or deletes them when payload is nil. Collections such as
+// harddrives keep a record per sample, so the records are handled in pages
+// of one batch request each. Every page is listed again from the start, as
+// the updated records no longer match filter.
+func updateReconciled(ctx context.Context, env *Env, collection, keyField, filter string, payload map[string]interface{}, action string) error {
+	counts := map[string]int{}
+	defer func() {
+		keys := make([]string, 0, len(counts))
+		for key := range counts {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			log.Printf("%s: %s %s (%d records).", collection, key, action, counts[key])
+		}
+	}()
+
+	done := map[string]bool{}
+	for {
+		records, err := listReconciled(ctx, env.Client, collection, keyField, filter)
+		if err != nil {
+			return err
+		}
+		if len(records) == 0 {
+			return nil
+		}
+
+		requests := make([]pocketbase.BatchRequest, 0, len(records))
+		for _, record := range records {
+			if done[record.ID] {
+				return fmt.Errorf("failed to update %s: record %s still matches after the update", collection, record.ID)
+			}
+			done[record.ID] = true
+
+			if payload == nil {
+				requests = append(requests, pocketbase.DeleteRequest(collection, record.ID))
+			} else {
+				requests = append(requests, pocketbase.UpdateRequest(collection, record.ID, payload))
+			}
+		}
+
+		if err := env.sendRequests(ctx, requests); err != nil {
+			return fmt.Errorf("failed to update %s: %v", collection, err)
+		}
+		for _, record := range records {
+			counts[fmt.Sprint(record.Key)]++
+		}
+
+		if len(records) < pocketbase.MaxBatchRequests {
+			return nil
+		}
+	}
+}
+
+// reconciledRecord is the ID and key of a record touched by reconcile.
+type reconciledRecord struct {
+	ID  string
+	Key interface{}
+}
+
+// listReconciled returns the ID and key field of the first records matching
+// filter, as many as fit in one batch request.
+func listReconciled(ctx context.Context, client *pocketbase.Client, collection, keyField, filter string) ([]reconciledRecord, error) {
+	result, err := pocketbase.List[map[string]interface{}](ctx, client, collection, pocketbase.ListOptions{
+		Page:      1,
+		PerPage:   pocketbase.MaxBatchRequests,
+		Filter:    filter,
+		Fields:    "id," + keyField,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %v", collection, err)
+	}
+
+	records := make([]reconciledRecord, 0, len(result.Items))
+	for _, item := range result.Items {
+		id, _ := item["id"].(string)
+		records = append(records, reconciledRecord{ID: id, Key: item[keyField]})
+	}
+	return records, nil
+}
@@ -0,0 +1,177 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/rdap"
+)
+
+// rdapBootstrapMaxAge is how long the cached bootstrap registry is used
+// before it is downloaded again.
+const rdapBootstrapMaxAge = 24 * time.Hour
+
+// rdapRetryInterval is how long a failed lookup is cached, unless the lookup
+// interval is shorter.
+const rdapRetryInterval = time.Hour
+
+// rdapTimeout bounds a single RDAP request.
+const rdapTimeout = 15 * time.Second
+
+// registration is the cached result of the RDAP lookup of a domain.
+type registration struct {
+	Checked time.Time          `json:"checked"`
+	Result  *rdap.Registration `json:"result,omitempty"`
+	Error   string             `json:"error,omitempty"`
+}
+
+// cachedBootstrap is the bootstrap registry as stored in the state dir.
+type cachedBootstrap struct {
+	Fetched   time.Time       `json:"fetched"`
+	Bootstrap *rdap.Bootstrap `json:"bootstrap"`
+}
+
+// registrationLookup looks up domain registrations via RDAP. Results are
+// cached in the state dir for the configured interval, so registries are not
+// queried on every run.
+type registrationLookup struct {
+	env    *Env
+	client *rdap.Client
+	cache  map[string]registration
+	seen   map[string]bool
+}
+
+func newRegistrationLookup(env *Env) *registrationLookup {
+	lookup := &registrationLookup{
+		env: env,
+		client: &rdap.Client{
+			HTTPClient: &http.Client{Timeout: rdapTimeout},
+			Server:     env.Config.RDAP.Server,
+		},
+		cache: map[string]registration{},
+		seen:  map[string]bool{},
+	}
+
+	if _, err := env.State.Load("rdap", &lookup.cache); err != nil {
+		log.Printf("Ignoring cached registrations: %v", err)
+	}
+	return lookup
+}
+
+// lookup returns the registration of domain, from the cache when it is
+// recent enough.
+func (l *registrationLookup) lookup(ctx context.Context, domain string) registration {
+	l.seen[domain] = true
+
+	maxAge := time.Duration(l.env.Config.RDAP.Interval)
+	cached, found := l.cache[domain]
+	if found && cached.Error != "" && rdapRetryInterval < maxAge {
+		maxAge = rdapRetryInterval
+	}
+	if found && time.Since(cached.Checked) < maxAge {
+		return cached
+	}
+
+	result := registration{Checked: time.Now().UTC()}
+	if err := l.loadBootstrap(ctx); err != nil {
+		result.Error = err.Error()
+	} else if result.Result, err = l.query(ctx, domain); err != nil {
+		result.Error = err.Error()
+	}
+	if result.Error != "" {
+		log.Printf("Error looking up registration of domain %s: %s", domain, result.Error)
+	}
+
+	l.cache[domain] = result
+	return result
+}
+
+// query looks up domain, and the parent domains of a subdomain until the
+// registered domain is found.
+func (l *registrationLookup) query(ctx context.Context, domain string) (*rdap.Registration, error) {
+	name := strings.ToLower(strings.TrimSuffix(domain, "."))
+	for {
+		result, err := l.client.Domain(ctx, name)
+		if !errors.Is(err, rdap.ErrNotFound) || strings.Count(name, ".") < 2 {
+			return result, err
+		}
+		name = name[strings.Index(name, ".")+1:]
+	}
+}
+
+// loadBootstrap sets the bootstrap registry of the client, from the state
+// dir or downloaded when the cached copy is missing or outdated. A cached
+// copy is still used when the download fails.
+func (l *registrationLookup) loadBootstrap(ctx context.Context) error {
+	if l.client.Server != "" || l.client.Bootstrap != nil {
+		return nil
+	}
+
+	var cached cachedBootstrap
+	found, err := l.env.State.Load("rdap-bootstrap", &cached)
+	if err != nil {
+		log.Printf("Ignoring cached RDAP bootstrap registry: %v", err)
+	}
+	if found && cached.Bootstrap != nil && time.Since(cached.Fetched) < rdapBootstrapMaxAge {
+		l.client.Bootstrap = cached.Bootstrap
+		return nil
+	}
+
+	url := l.env.Config.RDAP.BootstrapURL
+	if url == "" {
+		url = rdap.DefaultBootstrapURL
+	}
+	bootstrap, err := rdap.FetchBootstrap(ctx, l.client.HTTPClient, url)
+	if err != nil {
+		if found && cached.Bootstrap != nil {
+			log.Printf("Using outdated RDAP bootstrap registry: %v", err)
+			l.client.Bootstrap = cached.Bootstrap
+			return nil
+		}
+		return err
+	}
+
+	l.client.Bootstrap = bootstrap
+	err = l.env.State.Save("rdap-bootstrap", cachedBootstrap{Fetched: time.Now(), Bootstrap: bootstrap})
+	if err != nil {
+		log.Printf("Error saving RDAP bootstrap registry: %v", err)
+	}
+	return nil
+}
+
+// save stores the cached registrations of the domains looked up in this run.
+func (l *registrationLookup) save() {
+	for domain := range l.cache {
+		if !l.seen[domain] {
+			delete(l.cache, domain)
+		}
+	}
+
+	if err := l.env.State.Save("rdap", l.cache); err != nil {
+		log.Printf("Error saving registrations: %v", err)
+	}
+}
+
+// registrationPayload returns the domain record fields of a registration.
+func registrationPayload(r registration) map[string]interface{} {
+	payload := map[string]interface{}{
+		"registrationChecked": r.Checked.Format(time.RFC3339),
+		"registrationError":   r.Error,
+	}
+	if r.Result == nil {
+		return payload
+	}
+
+	payload["registrar"] = r.Result.Registrar
+	payload["registrationStatus"] = r.Result.Status
+	if !r.Result.Expires.IsZero() {
+		payload["registrationExpires"] = r.Result.Expires.UTC().Format(time.RFC3339)
+		payload["registrationDaysRemaining"] = int(math.Floor(time.Until(r.Result.Expires).Hours() / 24))
+	}
+	return payload
+}
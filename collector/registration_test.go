@@ -0,0 +1,53 @@
+package collector
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/Server-Manager-cloud/cronjobs/rdap"
+)
+
+func TestRegistrationQueryWalksUp(t *testing.T) {
+	var mu sync.Mutex
+	var queried []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/domain/")
+		mu.Lock()
+		queried = append(queried, name)
+		mu.Unlock()
+
+		if name != "example.com" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`{"ldhName": "example.com", "entities": [{"handle": "R", "roles": ["registrar"]}]}`))
+	}))
+	defer server.Close()
+
+	lookup := &registrationLookup{client: &rdap.Client{HTTPClient: server.Client(), Server: server.URL}}
+
+	result, err := lookup.query(context.Background(), "WWW.Shop.Example.com.")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Domain != "example.com" || result.Registrar != "R" {
+		t.Errorf("result = %+v, want the registration of example.com", result)
+	}
+	if got := strings.Join(queried, ","); got != "www.shop.example.com,shop.example.com,example.com" {
+		t.Errorf("queried %s", got)
+	}
+
+	// The registered domain itself is not found: stop there.
+	queried = nil
+	if _, err := lookup.query(context.Background(), "sub.missing.com"); !errors.Is(err, rdap.ErrNotFound) {
+		t.Errorf("error = %v, want ErrNotFound", err)
+	}
+	if got := strings.Join(queried, ","); got != "sub.missing.com,missing.com" {
+		t.Errorf("queried %s", got)
+	}
+}
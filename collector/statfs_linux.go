@@ -0,0 +1,27 @@
+package collector
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// statFilesystem returns the capacity of the file system mounted at path.
+func statFilesystem(path string) (filesystemStats, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return filesystemStats{}, fmt.Errorf("statfs %s: %v", path, err)
+	}
+
+	blockSize := uint64(st.Frsize)
+	if blockSize == 0 {
+		blockSize = uint64(st.Bsize)
+	}
+
+	return filesystemStats{
+		Total:       st.Blocks * blockSize,
+		Free:        st.Bavail * blockSize,
+		Used:        (st.Blocks - st.Bfree) * blockSize,
+		InodesTotal: st.Files,
+		InodesFree:  st.Ffree,
+	}, nil
+}
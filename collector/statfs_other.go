@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// statFilesystem is only implemented on Linux.
+func statFilesystem(path string) (filesystemStats, error) {
+	return filesystemStats{}, fmt.Errorf("statfs is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,168 @@
+package collector
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+	"github.com/Server-Manager-cloud/cronjobs/spool"
+)
+
+// create adds a record to collection. When PocketBase cannot be reached the
+// write is queued and replayed later, and no error is returned. While
+// batching the record is only sent by Flush.
+//
+// The record ID is chosen here, so a create that is sent again, after a
+// timeout or from the queue, cannot add the record twice.
+func (env *Env) create(ctx context.Context, collection string, payload interface{}) error {
+	if record, ok := payload.(map[string]interface{}); ok && record["id"] == nil {
+		record["id"] = newRecordID()
+	}
+
+	if env.batch != nil {
+		env.batch.add(collection, payload)
+		return nil
+	}
+
+	return env.send(spool.Op{Action: spool.ActionCreate, Collection: collection}, payload, func() error {
+		return env.createRecord(ctx, collection, payload)
+	})
+}
+
+// createRecord sends a create. When a record with the ID of the payload
+// exists already, an earlier attempt was committed even though it failed,
+// and the record counts as created.
+func (env *Env) createRecord(ctx context.Context, collection string, payload interface{}) error {
+	err := env.Client.Create(ctx, collection, payload, nil)
+	if pocketbase.IsNotUnique(err, "id") {
+		return nil
+	}
+	return err
+}
+
+// recordIDAlphabet and recordIDLength match the IDs PocketBase generates.
+const (
+	recordIDAlphabet = "abcdefghijklmnopqrstuvwxyz0123456789"
+	recordIDLength   = 15
+)
+
+// newRecordID returns a random record ID.
+func newRecordID() string {
+	// Bytes above the largest multiple of the alphabet size are skipped, so
+	// every character is equally likely
+	limit := 256 - 256%len(recordIDAlphabet)
+
+	id := make([]byte, 0, recordIDLength)
+	random := make([]byte, 2*recordIDLength)
+	for len(id) < recordIDLength {
+		if _, err := rand.Read(random); err != nil {
+			panic(fmt.Sprintf("failed to generate record ID: %v", err))
+		}
+		for _, b := range random {
+			if int(b) < limit && len(id) < recordIDLength {
+				id = append(id, recordIDAlphabet[int(b)%len(recordIDAlphabet)])
+			}
+		}
+	}
+	return string(id)
+}
+
+// update changes the record id of collection, queueing the write like create.
+func (env *Env) update(ctx context.Context, collection, id string, payload interface{}) error {
+	return env.send(spool.Op{Action: spool.ActionUpdate, Collection: collection, ID: id}, payload, func() error {
+		return env.Client.Update(ctx, collection, id, payload, nil)
+	})
+}
+
+// upsert updates the record of collection matching filter or creates one,
+// queueing the write like create. Only the last queued upsert of a record is
+// replayed. Like create it chooses the ID of a new record.
+func (env *Env) upsert(ctx context.Context, collection, filter string, payload interface{}) error {
+	id := newRecordID()
+	return env.send(spool.Op{Action: spool.ActionUpsert, Collection: collection, ID: id, Filter: filter}, payload, func() error {
+		return env.Client.Upsert(ctx, collection, filter, id, payload, nil)
+	})
+}
+
+// send performs the write op with write. While older writes are queued,
+// also during their backoff, op is queued behind them instead, so that
+// PocketBase receives the writes in order.
+func (env *Env) send(op spool.Op, payload interface{}, write func() error) error {
+	if !env.queued() {
+		return env.spoolOnFailure(write(), op, payload)
+	}
+
+	if err := env.enqueue(op, payload); err != nil {
+		return fmt.Errorf("failed to queue %s of %s: %v", op.Action, op.Collection, err)
+	}
+	log.Printf("Queued %s of %s behind the writes waiting for PocketBase.", op.Action, op.Collection)
+	return nil
+}
+
+// queued reports whether writes are waiting to be replayed.
+func (env *Env) queued() bool {
+	return env.Spool != nil && env.Spool.Pending()
+}
+
+// spoolOnFailure queues op when err means PocketBase was unreachable.
+func (env *Env) spoolOnFailure(err error, op spool.Op, payload interface{}) error {
+	if env.Spool == nil || err == nil || !pocketbase.IsRetryable(err) {
+		return err
+	}
+
+	if spoolErr := env.enqueue(op, payload); spoolErr != nil {
+		return fmt.Errorf("%v (and failed to queue it: %v)", err, spoolErr)
+	}
+	log.Printf("PocketBase unreachable, queued %s of %s for later: %v", op.Action, op.Collection, err)
+	return nil
+}
+
+// enqueue adds op with payload as body to the queue.
+func (env *Env) enqueue(op spool.Op, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode write: %v", err)
+	}
+	op.Time = time.Now().UTC()
+	op.Body = body
+	return env.Spool.Add(op)
+}
+
+// replay sends the queued writes. Writes PocketBase rejects are dropped,
+// the others stay queued until it is reachable again.
+func (env *Env) replay(ctx context.Context) {
+	if env.Spool == nil {
+		return
+	}
+
+	sent, err := env.Spool.Replay(ctx, func(ctx context.Context, op spool.Op) error {
+		var err error
+		switch op.Action {
+		case spool.ActionCreate:
+			err = env.createRecord(ctx, op.Collection, op.Body)
+		case spool.ActionUpdate:
+			err = env.Client.Update(ctx, op.Collection, op.ID, op.Body, nil)
+		case spool.ActionUpsert:
+			err = env.Client.Upsert(ctx, op.Collection, op.Filter, op.ID, op.Body, nil)
+		default:
+			log.Printf("Dropping queued write with unknown action %q", op.Action)
+			return nil
+		}
+
+		if err != nil && !pocketbase.IsRetryable(err) {
+			log.Printf("Dropping queued %s of %s from %s: %v", op.Action, op.Collection, op.Time.Format(time.RFC3339), err)
+			return nil
+		}
+		return err
+	})
+	if sent > 0 {
+		log.Printf("Replayed %d queued writes.", sent)
+	}
+	if err != nil {
+		log.Printf("Error replaying queued writes: %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/pocketbase"
+	"github.com/Server-Manager-cloud/cronjobs/spool"
+)
+
+func TestNewRecordID(t *testing.T) {
+	valid := regexp.MustCompile(`^[a-z0-9]{15}$`)
+	seen := map[string]bool{}
+	for i := 0; i < 1000; i++ {
+		id := newRecordID()
+		if !valid.MatchString(id) {
+			t.Fatalf("newRecordID() = %q, want 15 characters of a-z and 0-9", id)
+		}
+		if seen[id] {
+			t.Fatalf("newRecordID() returned %q twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+// TestCreateIsIdempotent sends a create whose first attempt is committed
+// but answered with a 503, as after a timeout. The retry is rejected for
+// the duplicate ID and must count as success.
+func TestCreateIsIdempotent(t *testing.T) {
+	var mu sync.Mutex
+	created := map[string]bool{}
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		id, _ := body["id"].(string)
+
+		mu.Lock()
+		defer mu.Unlock()
+		attempts++
+
+		w.Header().Set("Content-Type", "application/json")
+		if created[id] {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": 400, "message": "Failed to create record.", "data": {"id": {"code": "validation_not_unique", "message": "Value must be unique."}}}`))
+			return
+		}
+		created[id] = true
+		if attempts == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": 503, "message": "Timeout."}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client := pocketbase.NewClient(server.URL)
+	client.Retry.BaseDelay = time.Millisecond
+	env := &Env{Client: client}
+
+	payload := map[string]interface{}{"value": 1}
+	if err := env.create(context.Background(), "load", payload); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if attempts != 2 || len(created) != 1 {
+		t.Errorf("%d attempts created %d records, want 2 attempts and 1 record", attempts, len(created))
+	}
+	if _, ok := payload["id"].(string); !ok {
+		t.Errorf("payload has no record ID: %v", payload)
+	}
+}
+
+// TestWritesQueuedBehindPending checks that a write is not sent while older
+// writes are queued, so that the replay cannot overwrite it.
+func TestWritesQueuedBehindPending(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	queue := &spool.Spool{Dir: t.TempDir()}
+	if err := queue.Add(spool.Op{Action: spool.ActionUpdate, Collection: "server_os", ID: "abc", Body: json.RawMessage(`{"kernel":"6.1"}`)}); err != nil {
+		t.Fatal(err)
+	}
+	env := &Env{Client: pocketbase.NewClient(server.URL), Spool: queue}
+
+	if err := env.update(context.Background(), "server_os", "abc", map[string]interface{}{"kernel": "6.8"}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if requests != 0 {
+		t.Errorf("%d requests sent while writes were queued, want 0", requests)
+	}
+
+	var kernels []string
+	sent, err := queue.Replay(context.Background(), func(ctx context.Context, op spool.Op) error {
+		var body map[string]string
+		json.Unmarshal(op.Body, &body)
+		kernels = append(kernels, body["kernel"])
+		return nil
+	})
+	if err != nil || sent != 2 {
+		t.Fatalf("Replay() = %d, %v, want 2, nil", sent, err)
+	}
+	if len(kernels) != 2 || kernels[0] != "6.1" || kernels[1] != "6.8" {
+		t.Errorf("replayed kernels %v, want [6.1 6.8]", kernels)
+	}
+}
@@ -0,0 +1,179 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net"
+	"strconv"
+	"time"
+)
+
+func init() {
+	Register(tlsCollector{})
+}
+
+// tlsCollector connects to the web server of this server for every domain
+// of its certificates and reports the certificate actually served to the
+// tls_probes collection. Certbot's view can differ from what the web server
+// serves, e.g. after a renewal without reload.
+type tlsCollector struct{}
+
+// tlsProbe is the result of a TLS handshake with one domain.
+type tlsProbe struct {
+	// Domain is the domain record of the certificate and Name the domain
+	// sent as SNI.
+	Domain  Domain
+	Name    string
+	Address string
+
+	// Error is set when no handshake could be completed.
+	Error string
+
+	Version          string
+	ConnectLatency   time.Duration
+	HandshakeLatency time.Duration
+	Certificate      certificateSummary
+
+	// HostnameValid reports whether the certificate covers the domain.
+	HostnameValid bool
+
+	// ChainError is why the served chain does not verify, empty when it does.
+	ChainError string
+}
+
+func (tlsCollector) Name() string { return "tls" }
+
+func (tlsCollector) Collect(ctx context.Context, env *Env) (interface{}, error) {
+	// Get domains from PocketBase
+	domains, err := getPocketBaseRecords(ctx, env.Client, env.ServerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve domains: %v", err)
+	}
+
+	settings := env.Config.TLS
+	address := net.JoinHostPort(settings.Address, strconv.Itoa(settings.Port))
+	var probes []tlsProbe
+	for _, domain := range domains {
+		for _, name := range domain.names() {
+			probe := probeTLS(ctx, address, name, time.Duration(settings.Timeout), nil)
+			probe.Domain = domain
+			probe.Name = name
+			probes = append(probes, probe)
+		}
+	}
+
+	return probes, nil
+}
+
+func (tlsCollector) Submit(ctx context.Context, env *Env, data interface{}) error {
+	now := time.Now()
+
+	var errs []error
+	for _, probe := range data.([]tlsProbe) {
+		payload := map[string]interface{}{
+			"domain":    probe.Domain.ID,
+			"name":      probe.Name,
+			"address":   probe.Address,
+			"reachable": probe.Error == "",
+			"error":     probe.Error,
+			"server":    env.ServerID,
+			"sampledAt": env.sampledAt(),
+			"runId":     env.RunID,
+		}
+		if probe.Error == "" {
+			payload["tlsVersion"] = probe.Version
+			payload["connectMs"] = milliseconds(probe.ConnectLatency)
+			payload["handshakeMs"] = milliseconds(probe.HandshakeLatency)
+			payload["certDomains"] = probe.Certificate.Domains
+			payload["certIssuer"] = probe.Certificate.Issuer
+			payload["certKeyType"] = probe.Certificate.KeyType
+			payload["certExpires"] = probe.Certificate.NotAfter.UTC().Format(time.RFC3339)
+			payload["certDaysRemaining"] = probe.Certificate.daysRemaining(now)
+			payload["hostnameValid"] = probe.HostnameValid
+			payload["chainValid"] = probe.ChainError == ""
+			payload["chainError"] = probe.ChainError
+		}
+
+		err := env.create(ctx, "tls_probes", payload)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to send TLS probe of %s: %v", probe.Name, err))
+			continue
+		}
+
+		if probe.Error != "" {
+			log.Printf("TLS probe of %s failed: %s", probe.Name, probe.Error)
+		} else {
+			log.Printf("TLS probe of %s successfully reported! %s, certificate expires in %d days", probe.Name, probe.Version, probe.Certificate.daysRemaining(now))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// probeTLS performs a TLS handshake with address using serverName for SNI.
+// The certificate is not verified during the handshake so that expired or
+// mismatching certificates are still reported; it is verified afterwards
+// against roots (the system roots when nil).
+func probeTLS(ctx context.Context, address, serverName string, timeout time.Duration, roots *x509.CertPool) tlsProbe {
+	probe := tlsProbe{Address: address}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	var dialer net.Dialer
+	rawConn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	defer rawConn.Close()
+	probe.ConnectLatency = time.Since(start)
+
+	conn := tls.Client(rawConn, &tls.Config{
+		ServerName:         serverName,
+		InsecureSkipVerify: true,
+	})
+
+	start = time.Now()
+	if err := conn.HandshakeContext(ctx); err != nil {
+		probe.Error = err.Error()
+		return probe
+	}
+	probe.HandshakeLatency = time.Since(start)
+
+	state := conn.ConnectionState()
+	probe.Version = tls.VersionName(state.Version)
+	if len(state.PeerCertificates) == 0 {
+		probe.Error = "server sent no certificate"
+		return probe
+	}
+
+	leaf := state.PeerCertificates[0]
+	probe.Certificate = summarizeCertificate(leaf)
+	probe.HostnameValid = leaf.VerifyHostname(serverName) == nil
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err = leaf.Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+	})
+	if err != nil {
+		probe.ChainError = err.Error()
+	}
+
+	return probe
+}
+
+// milliseconds converts a duration to milliseconds with two decimals.
+func milliseconds(d time.Duration) float64 {
+	return math.Round(float64(d)/float64(time.Millisecond)*100) / 100
+}
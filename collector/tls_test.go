@@ -0,0 +1,102 @@
+package collector
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTLSServer starts a local HTTPS server. Its certificate covers
+// example.com and 127.0.0.1.
+func newTLSServer(t *testing.T, maxVersion uint16) (*httptest.Server, *x509.CertPool) {
+	t.Helper()
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	server.TLS = &tls.Config{MaxVersion: maxVersion}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	return server, roots
+}
+
+func TestProbeTLS(t *testing.T) {
+	server, roots := newTLSServer(t, 0)
+
+	probe := probeTLS(context.Background(), server.Listener.Addr().String(), "example.com", 5*time.Second, roots)
+	if probe.Error != "" {
+		t.Fatalf("probe failed: %s", probe.Error)
+	}
+	if probe.Version != tls.VersionName(tls.VersionTLS13) {
+		t.Errorf("Version = %q, want %q", probe.Version, tls.VersionName(tls.VersionTLS13))
+	}
+	if !probe.HostnameValid {
+		t.Error("HostnameValid = false, want true")
+	}
+	if probe.ChainError != "" {
+		t.Errorf("ChainError = %q, want none", probe.ChainError)
+	}
+	if probe.Certificate.NotAfter.IsZero() || len(probe.Certificate.Domains) == 0 {
+		t.Errorf("Certificate = %+v, want the served certificate", probe.Certificate)
+	}
+}
+
+func TestProbeTLSVersion(t *testing.T) {
+	server, roots := newTLSServer(t, tls.VersionTLS12)
+
+	probe := probeTLS(context.Background(), server.Listener.Addr().String(), "example.com", 5*time.Second, roots)
+	if probe.Error != "" {
+		t.Fatalf("probe failed: %s", probe.Error)
+	}
+	if probe.Version != tls.VersionName(tls.VersionTLS12) {
+		t.Errorf("Version = %q, want %q", probe.Version, tls.VersionName(tls.VersionTLS12))
+	}
+}
+
+func TestProbeTLSWrongServerName(t *testing.T) {
+	server, roots := newTLSServer(t, 0)
+
+	probe := probeTLS(context.Background(), server.Listener.Addr().String(), "wrong.test", 5*time.Second, roots)
+	if probe.Error != "" {
+		t.Fatalf("probe failed: %s", probe.Error)
+	}
+	if probe.HostnameValid {
+		t.Error("HostnameValid = true for a name the certificate does not cover")
+	}
+}
+
+func TestProbeTLSUntrustedChain(t *testing.T) {
+	server, _ := newTLSServer(t, 0)
+
+	probe := probeTLS(context.Background(), server.Listener.Addr().String(), "example.com", 5*time.Second, x509.NewCertPool())
+	if probe.Error != "" {
+		t.Fatalf("probe failed: %s", probe.Error)
+	}
+	if probe.ChainError == "" {
+		t.Error("ChainError is empty without the test root")
+	}
+}
+
+func TestProbeTLSConnectionRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	probe := probeTLS(context.Background(), address, "example.com", 5*time.Second, nil)
+	if !strings.Contains(probe.Error, "refused") {
+		t.Errorf("Error = %q, want connection refused", probe.Error)
+	}
+	if probe.Version != "" || probe.HostnameValid {
+		t.Errorf("probe = %+v, want no handshake results", probe)
+	}
+}
@@ -0,0 +1,26 @@
+package collector
+
+import "syscall"
+
+// uname returns the kernel release and the machine hardware name, e.g.
+// "6.8.0-45-generic" and "x86_64".
+func uname() (release, machine string, err error) {
+	var uts syscall.Utsname
+	if err := syscall.Uname(&uts); err != nil {
+		return "", "", err
+	}
+	return utsString(uts.Release[:]), utsString(uts.Machine[:]), nil
+}
+
+// utsString converts a NUL terminated Utsname field. The fields are int8 or
+// uint8 arrays depending on the architecture.
+func utsString[T int8 | uint8](field []T) string {
+	b := make([]byte, 0, len(field))
+	for _, c := range field {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package collector
+
+import (
+	"fmt"
+	"runtime"
+)
+
+// uname is only implemented on Linux.
+func uname() (release, machine string, err error) {
+	return "", "", fmt.Errorf("uname is not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"time"
+)
+
+// DefaultSchedules are used for collectors without a schedule in smc.json.
+var DefaultSchedules = map[string]string{
+	"cpu":        "1h",
+	"harddrive":  "1h",
+	"memory":     "5m",
+	"load":       "5m",
+	"network":    "5m",
+	"diskio":     "5m",
+	"tls":        "1h",
+	"domains":    "1m",
+	"nameserver": "1m",
+	"os":         "1m",
+}
+
+// DefaultExcludes are used for collectors without exclude patterns in
+// smc.json.
+var DefaultExcludes = map[string][]string{
+	"diskio":  {"loop*", "ram*", "zram*", "sr*", "fd*"},
+	"network": {"lo", "veth*", "docker*", "br-*", "virbr*", "vnet*", "ifb*", "cali*", "flannel*", "cni*"},
+}
+
+// PseudoFSTypes are file system types the harddrive collector skips unless
+// smc.json sets excludeFsTypes.
+var PseudoFSTypes = []string{
+	"autofs", "binfmt_misc", "bpf", "cgroup", "cgroup2", "configfs", "debugfs",
+	"devpts", "devtmpfs", "efivarfs", "fuse.gvfsd-fuse", "fuse.lxcfs", "fusectl",
+	"hugetlbfs", "mqueue", "nsfs", "overlay", "proc", "pstore", "ramfs",
+	"rpc_pipefs", "securityfs", "selinuxfs", "squashfs", "sysfs", "tmpfs",
+	"tracefs",
+}
+
+// DefaultLetsEncryptDir is where certbot keeps the current certificates.
+const DefaultLetsEncryptDir = "/etc/letsencrypt/live"
+
+// DefaultTLSAddress, DefaultTLSPort and DefaultTLSTimeout are used by the
+// tls collector unless smc.json sets them.
+const (
+	DefaultTLSAddress = "127.0.0.1"
+	DefaultTLSPort    = 443
+	DefaultTLSTimeout = Duration(10 * time.Second)
+)
+
+// DefaultRDAPInterval is how often the registration of a domain is looked
+// up unless smc.json sets rdap.interval.
+const DefaultRDAPInterval = Duration(24 * time.Hour)
+
+// DefaultStateDir is where collectors keep state between runs unless
+// smc.json sets stateDir.
+const DefaultStateDir = "state"
+
+// Config is the content of smc.json.
+type Config struct {
+	// Domain is the PocketBase instance the collectors submit to.
+	Domain string `json:"domain"`
+
+	// StateDir is the directory collectors keep state in between runs.
+	StateDir string `json:"stateDir"`
+
+	// Collectors holds per-collector settings keyed by collector name.
+	Collectors map[string]CollectorConfig `json:"collectors"`
+
+	// Domains configures the domains collector.
+	Domains DomainsConfig `json:"domains"`
+
+	// TLS configures the tls collector.
+	TLS TLSConfig `json:"tls"`
+
+	// DNS configures the DNS lookups of the nameserver collector.
+	DNS DNSConfig `json:"dns"`
+
+	// RDAP configures the registration lookups of the nameserver collector.
+	RDAP RDAPConfig `json:"rdap"`
+
+	// OS configures the os collector.
+	OS OSConfig `json:"os"`
+
+	// Spool configures the queue of writes PocketBase did not accept.
+	Spool SpoolConfig `json:"spool"`
+
+	// PocketBase configures timeouts and retries of PocketBase requests.
+	PocketBase PocketBaseConfig `json:"pocketbase"`
+}
+
+// DomainsConfig configures the domains collector.
+type DomainsConfig struct {
+	// LiveDir is the certbot directory holding one subdirectory per
+	// certificate.
+	LiveDir string `json:"liveDir"`
+}
+
+// CollectorConfig holds the settings of a single collector.
+type CollectorConfig struct {
+	// Schedule is a duration ("1h") or a cron expression ("0 3 * * *").
+	Schedule string `json:"schedule"`
+
+	// Jitter randomly delays each scheduled run by up to this duration.
+	Jitter Duration `json:"jitter"`
+
+	// Include and Exclude are glob patterns selecting the items a collector
+	// reports, e.g. network interfaces. An empty Include matches everything
+	// and Exclude takes precedence.
+	Include []string `json:"include"`
+	Exclude []string `json:"exclude"`
+
+	// ExcludeFSTypes lists file system types the harddrive collector skips.
+	ExcludeFSTypes []string `json:"excludeFsTypes"`
+
+	// DeleteGoneAfter is how long records of items that disappeared, e.g.
+	// unmounted file systems, are kept marked as gone before they are
+	// deleted. Zero keeps them.
+	DeleteGoneAfter Duration `json:"deleteGoneAfter"`
+}
+
+// Matches reports whether the item called name passes the Include and
+// Exclude patterns.
+func (c CollectorConfig) Matches(name string) bool {
+	for _, pattern := range c.Exclude {
+		if ok, _ := path.Match(pattern, name); ok {
+			return false
+		}
+	}
+
+	if len(c.Include) == 0 {
+		return true
+	}
+	for _, pattern := range c.Include {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// TLSConfig configures the tls collector.
+type TLSConfig struct {
+	// Address is the address of this server's web server the domains are
+	// probed on, with the domain sent as SNI.
+	Address string `json:"address"`
+
+	// Port is the port the domains are probed on.
+	Port int `json:"port"`
+
+	// Timeout bounds the connection and handshake with each domain.
+	Timeout Duration `json:"timeout"`
+}
+
+// DNSConfig configures the DNS lookups of the nameserver collector.
+type DNSConfig struct {
+	// Resolver is the recursive resolver to query, e.g. "1.1.1.1:53". The
+	// first nameserver of /etc/resolv.conf is used when empty.
+	Resolver string `json:"resolver"`
+
+	// ProviderRules is the JSON file mapping nameserver hostnames to DNS
+	// providers, relative to smc.json. The rules built into the agent are
+	// used when empty.
+	ProviderRules string `json:"providerRules"`
+
+	// Providers maps a DNS provider name to additional patterns of its
+	// nameserver hostnames. A pattern is a domain suffix
+	// ("ns.cloudflare.com") or a glob ("*.awsdns-*").
+	Providers map[string][]string `json:"providers"`
+
+	// PublicAddresses are the addresses the domains of this server should
+	// resolve to. When empty the public addresses of the network interfaces
+	// are used, which misses addresses behind NAT or a load balancer.
+	PublicAddresses []string `json:"publicAddresses"`
+}
+
+// SpoolConfig configures the queue of writes that failed because PocketBase
+// was unreachable. The queue is kept in the state directory.
+type SpoolConfig struct {
+	// Disabled drops failed writes instead of queueing them.
+	Disabled bool `json:"disabled"`
+
+	// MaxEntries and MaxAge cap the queue, the oldest writes are dropped
+	// first. The defaults of the spool package are used when zero.
+	MaxEntries int      `json:"maxEntries"`
+	MaxAge     Duration `json:"maxAge"`
+}
+
+// PocketBaseConfig configures timeouts and retries of PocketBase requests.
+// The defaults of the pocketbase package are used for zero values.
+type PocketBaseConfig struct {
+	// Timeout bounds every single request.
+	Timeout Duration `json:"timeout"`
+
+	// MaxAttempts is how often a request failing with a network error, a
+	// timeout or HTTP 408, 429 or 5xx is sent, 1 disables retries.
+	MaxAttempts int `json:"maxAttempts"`
+
+	// RetryDelay is the delay before the first retry, doubled for every
+	// further one up to MaxRetryDelay.
+	RetryDelay    Duration `json:"retryDelay"`
+	MaxRetryDelay Duration `json:"maxRetryDelay"`
+
+	// BreakerThreshold is the number of failed requests in a row after which
+	// no requests are sent for BreakerCooldown. A negative threshold disables
+	// the circuit breaker.
+	BreakerThreshold int      `json:"breakerThreshold"`
+	BreakerCooldown  Duration `json:"breakerCooldown"`
+}
+
+// OSConfig configures the os collector.
+type OSConfig struct {
+	// History is the collection a record is added to whenever the
+	// distribution or kernel changes, e.g. "server_os_history". No history
+	// is kept when empty.
+	History string `json:"history"`
+}
+
+// RDAPConfig configures the registration lookups of the nameserver collector.
+type RDAPConfig struct {
+	// BootstrapURL is the registry mapping top level domains to RDAP
+	// servers. Defaults to the IANA registry.
+	BootstrapURL string `json:"bootstrapUrl"`
+
+	// Server, when set, is queried for every domain instead of the server
+	// from the bootstrap registry, e.g. a local stand-in server.
+	Server string `json:"server"`
+
+	// Interval is how often the registration of a domain is looked up.
+	// Defaults to DefaultRDAPInterval.
+	Interval Duration `json:"interval"`
+}
+
+// Duration is a time.Duration written as a string ("30s") in JSON.
+type Duration time.Duration
+
+// UnmarshalJSON parses a duration string such as "90s" or "5m".
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %v", err)
+	}
+
+	parsed, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+
+	*d = Duration(parsed)
+	return nil
+}
+
+// MarshalJSON writes the duration as a string.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// Load reads the smc.json configuration file.
+func Load(filePath string) (*Config, error) {
+	// Open the smc.json file
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open smc.json file: %v", err)
+	}
+	defer file.Close()
+
+	// Parse the JSON content
+	var config Config
+	decoder := json.NewDecoder(file)
+	err = decoder.Decode(&config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse smc.json: %v", err)
+	}
+
+	if config.StateDir == "" {
+		config.StateDir = DefaultStateDir
+	}
+	if config.Domains.LiveDir == "" {
+		config.Domains.LiveDir = DefaultLetsEncryptDir
+	}
+	if config.TLS.Address == "" {
+		config.TLS.Address = DefaultTLSAddress
+	}
+	if config.TLS.Port == 0 {
+		config.TLS.Port = DefaultTLSPort
+	}
+	if config.TLS.Timeout == 0 {
+		config.TLS.Timeout = DefaultTLSTimeout
+	}
+	if config.DNS.ProviderRules != "" && !filepath.IsAbs(config.DNS.ProviderRules) {
+		config.DNS.ProviderRules = filepath.Join(filepath.Dir(filePath), config.DNS.ProviderRules)
+	}
+	if config.RDAP.Interval == 0 {
+		config.RDAP.Interval = DefaultRDAPInterval
+	}
+
+	return &config, nil
+}
+
+// Collector returns the settings of the named collector, falling back to
+// DefaultSchedules, DefaultExcludes and PseudoFSTypes when smc.json does not
+// set them.
+func (c *Config) Collector(name string) CollectorConfig {
+	collector := c.Collectors[name]
+	if collector.Schedule == "" {
+		collector.Schedule = DefaultSchedules[name]
+	}
+	if collector.Exclude == nil {
+		collector.Exclude = DefaultExcludes[name]
+	}
+	if collector.ExcludeFSTypes == nil {
+		collector.ExcludeFSTypes = PseudoFSTypes
+	}
+	return collector
+}
@@ -0,0 +1,52 @@
+// Package config loads the agent settings shared by all collectors.
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadEnv reads the .env file and loads its contents into environment variables.
+func LoadEnv(filePath string) error {
+	// Open the .env file
+	file, err := os.Open(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to open .env file: %v", err)
+	}
+	defer file.Close()
+
+	// Read each line from the .env file
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		// Skip empty lines or comments
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		// Split line into key and value
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			continue // Skip lines that don't have key-value pairs
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := strings.TrimSpace(parts[1])
+
+		// Set the environment variable
+		err := os.Setenv(key, value)
+		if err != nil {
+			return fmt.Errorf("failed to set environment variable: %v", err)
+		}
+	}
+
+	// Check for any errors during scanning
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("error reading .env file: %v", err)
+	}
+
+	return nil
+}
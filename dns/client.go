@@ -0,0 +1,198 @@
+package dns
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/rand"
+	"net"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultTimeout bounds a single query when Client.Timeout is zero.
+const DefaultTimeout = 5 * time.Second
+
+// fallbackResolver is used when /etc/resolv.conf lists no nameserver.
+const fallbackResolver = "1.1.1.1:53"
+
+// Client sends queries to a single recursive resolver.
+type Client struct {
+	// Server is the resolver address, e.g. "1.1.1.1:53".
+	Server string
+
+	// Timeout bounds each query. DefaultTimeout is used when zero.
+	Timeout time.Duration
+}
+
+// NewClient returns a client for server. An empty server selects the first
+// nameserver of /etc/resolv.conf, and a server without a port uses port 53.
+func NewClient(server string) *Client {
+	if server == "" {
+		server = SystemResolver()
+	}
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(strings.Trim(server, "[]"), "53")
+	}
+	return &Client{Server: server}
+}
+
+// SystemResolver returns the first nameserver of /etc/resolv.conf.
+func SystemResolver() string {
+	file, err := os.Open("/etc/resolv.conf")
+	if err != nil {
+		return fallbackResolver
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) >= 2 && fields[0] == "nameserver" {
+			return net.JoinHostPort(fields[1], "53")
+		}
+	}
+	return fallbackResolver
+}
+
+// Query sends a query for name and qtype. Responses truncated over UDP are
+// retried over TCP. A NXDOMAIN answer is returned as ErrNXDomain.
+func (c *Client) Query(ctx context.Context, name string, qtype uint16) (*Response, error) {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	id := uint16(rand.Intn(1 << 16))
+	query, err := buildQuery(id, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.exchange(ctx, "udp", query)
+	if err == nil && resp.Truncated {
+		resp, err = c.exchange(ctx, "tcp", query)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("query %s for %s: %v", c.Server, name, err)
+	}
+
+	if resp.ID != id {
+		return nil, fmt.Errorf("query %s for %s: response ID mismatch", c.Server, name)
+	}
+	switch resp.RCode {
+	case RCodeSuccess:
+		return resp, nil
+	case RCodeNXDomain:
+		return nil, fmt.Errorf("%s: %w", name, ErrNXDomain)
+	default:
+		return nil, fmt.Errorf("query %s for %s: response code %d", c.Server, name, resp.RCode)
+	}
+}
+
+// exchange sends query over network ("udp" or "tcp") and reads the response.
+func (c *Client) exchange(ctx context.Context, network string, query []byte) (*Response, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, c.Server)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if network == "udp" {
+		if _, err := conn.Write(query); err != nil {
+			return nil, err
+		}
+
+		buf := make([]byte, 4096)
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, err
+		}
+		return parseResponse(buf[:n])
+	}
+
+	// Over TCP messages are prefixed with their length
+	if _, err := conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(query)))); err != nil {
+		return nil, err
+	}
+	if _, err := conn.Write(query); err != nil {
+		return nil, err
+	}
+
+	var length [2]byte
+	if _, err := io.ReadFull(conn, length[:]); err != nil {
+		return nil, err
+	}
+	buf := make([]byte, binary.BigEndian.Uint16(length[:]))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return nil, err
+	}
+	return parseResponse(buf)
+}
+
+// Lookup returns the answer records of type qtype for name. Records of
+// other types in the answer, such as the CNAME chain leading to an A
+// record, are skipped.
+func (c *Client) Lookup(ctx context.Context, name string, qtype uint16) ([]Record, error) {
+	resp, err := c.Query(ctx, name, qtype)
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, record := range resp.Answers {
+		if record.Type == qtype {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// Zone is the zone a name belongs to, with its SOA record.
+type Zone struct {
+	Name string
+	SOA  SOA
+}
+
+// LookupZone finds the zone containing name. The SOA record is in the answer
+// when name is the zone apex, and in the authority section otherwise.
+func (c *Client) LookupZone(ctx context.Context, name string) (*Zone, error) {
+	resp, err := c.Query(ctx, name, TypeSOA)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, records := range [][]Record{resp.Answers, resp.Authority} {
+		for _, record := range records {
+			if record.Type == TypeSOA && record.SOA != nil {
+				return &Zone{Name: record.Name, SOA: *record.SOA}, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("no SOA record found for %s", name)
+}
+
+// LookupNS returns the nameserver hostnames of zone.
+func (c *Client) LookupNS(ctx context.Context, zone string) ([]string, error) {
+	records, err := c.Lookup(ctx, zone, TypeNS)
+	if err != nil {
+		return nil, err
+	}
+
+	var nameservers []string
+	for _, record := range records {
+		nameservers = append(nameservers, record.Target)
+	}
+	return nameservers, nil
+}
@@ -0,0 +1,81 @@
+package dns
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// answer returns a response to query with a single NS record of
+// ns1.example.net, or with the TC bit set and no answer when truncated is
+// true.
+func answer(query []byte, truncated bool) []byte {
+	resp := append([]byte(nil), query...)
+	binary.BigEndian.PutUint16(resp[2:], 0x8180)
+	if truncated {
+		resp[2] |= 1 << 1
+		return resp
+	}
+
+	binary.BigEndian.PutUint16(resp[6:], 1)
+	resp = append(resp, 0xc0, 0x0c)
+	resp = binary.BigEndian.AppendUint16(resp, TypeNS)
+	resp = binary.BigEndian.AppendUint16(resp, classINET)
+	resp = binary.BigEndian.AppendUint32(resp, 60)
+	target := []byte("\x03ns1\x07example\x03net\x00")
+	resp = binary.BigEndian.AppendUint16(resp, uint16(len(target)))
+	return append(resp, target...)
+}
+
+func TestQueryFallsBackToTCP(t *testing.T) {
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer udp.Close()
+	tcp, err := net.Listen("tcp", udp.LocalAddr().String())
+	if err != nil {
+		t.Skipf("cannot listen on the same TCP port: %v", err)
+	}
+	defer tcp.Close()
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := udp.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			udp.WriteTo(answer(buf[:n], true), addr)
+		}
+	}()
+	go func() {
+		for {
+			conn, err := tcp.Accept()
+			if err != nil {
+				return
+			}
+			var length [2]byte
+			if _, err := io.ReadFull(conn, length[:]); err == nil {
+				query := make([]byte, binary.BigEndian.Uint16(length[:]))
+				if _, err := io.ReadFull(conn, query); err == nil {
+					resp := answer(query, false)
+					conn.Write(binary.BigEndian.AppendUint16(nil, uint16(len(resp))))
+					conn.Write(resp)
+				}
+			}
+			conn.Close()
+		}
+	}()
+
+	client := NewClient(udp.LocalAddr().String())
+	nameservers, err := client.LookupNS(context.Background(), "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(nameservers) != 1 || nameservers[0] != "ns1.example.net" {
+		t.Errorf("nameservers = %v, want the NS record sent over TCP", nameservers)
+	}
+}
@@ -0,0 +1,277 @@
+// Package dns is a minimal DNS client for the record types the collectors
+// need and the standard library cannot look up (SOA) or cannot send to a
+// specific resolver.
+package dns
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// Record types.
+const (
+	TypeA     uint16 = 1
+	TypeNS    uint16 = 2
+	TypeCNAME uint16 = 5
+	TypeSOA   uint16 = 6
+	TypeMX    uint16 = 15
+	TypeTXT   uint16 = 16
+	TypeAAAA  uint16 = 28
+)
+
+const classINET uint16 = 1
+
+// Response codes.
+const (
+	RCodeSuccess  = 0
+	RCodeNXDomain = 3
+)
+
+// ErrNXDomain is returned when the queried name does not exist.
+var ErrNXDomain = errors.New("no such domain")
+
+var errTruncatedMessage = errors.New("truncated DNS message")
+
+// SOA is the start of authority of a zone.
+type SOA struct {
+	MName   string
+	RName   string
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	Minimum uint32
+}
+
+// Record is a resource record. Which fields are set depends on the type:
+// Target for NS, CNAME and MX, Preference for MX, Address for A and AAAA,
+// Text for TXT and SOA for SOA records.
+type Record struct {
+	Name       string
+	Type       uint16
+	TTL        uint32
+	Target     string
+	Preference uint16
+	Address    net.IP
+	Text       string
+	SOA        *SOA
+}
+
+// Response is a decoded DNS response.
+type Response struct {
+	ID        uint16
+	RCode     int
+	Truncated bool
+	Answers   []Record
+	Authority []Record
+}
+
+// buildQuery encodes a recursive query for name and qtype.
+func buildQuery(id uint16, name string, qtype uint16) ([]byte, error) {
+	msg := make([]byte, 12, 512)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 1<<8) // recursion desired
+	binary.BigEndian.PutUint16(msg[4:], 1)    // one question
+
+	msg, err := appendName(msg, name)
+	if err != nil {
+		return nil, err
+	}
+	msg = binary.BigEndian.AppendUint16(msg, qtype)
+	msg = binary.BigEndian.AppendUint16(msg, classINET)
+
+	return msg, nil
+}
+
+// appendName appends name in wire format (length prefixed labels).
+func appendName(msg []byte, name string) ([]byte, error) {
+	name = strings.TrimSuffix(name, ".")
+	if name == "" {
+		return append(msg, 0), nil
+	}
+
+	for _, label := range strings.Split(name, ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid domain name %q", name)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	return append(msg, 0), nil
+}
+
+// parseResponse decodes a response message.
+func parseResponse(msg []byte) (*Response, error) {
+	if len(msg) < 12 {
+		return nil, errTruncatedMessage
+	}
+
+	flags := binary.BigEndian.Uint16(msg[2:])
+	resp := &Response{
+		ID:        binary.BigEndian.Uint16(msg[0:]),
+		RCode:     int(flags & 0xf),
+		Truncated: flags&(1<<9) != 0,
+	}
+
+	questions := int(binary.BigEndian.Uint16(msg[4:]))
+	answers := int(binary.BigEndian.Uint16(msg[6:]))
+	authority := int(binary.BigEndian.Uint16(msg[8:]))
+
+	offset := 12
+	for i := 0; i < questions; i++ {
+		_, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, err
+		}
+		offset = next + 4 // type and class
+	}
+
+	var err error
+	resp.Answers, offset, err = readRecords(msg, offset, answers)
+	if err != nil {
+		return nil, err
+	}
+	resp.Authority, _, err = readRecords(msg, offset, authority)
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
+
+// readRecords decodes count resource records starting at offset.
+func readRecords(msg []byte, offset, count int) ([]Record, int, error) {
+	var records []Record
+	for i := 0; i < count; i++ {
+		name, next, err := readName(msg, offset)
+		if err != nil {
+			return nil, 0, err
+		}
+		if next+10 > len(msg) {
+			return nil, 0, errTruncatedMessage
+		}
+
+		record := Record{
+			Name: name,
+			Type: binary.BigEndian.Uint16(msg[next:]),
+			TTL:  binary.BigEndian.Uint32(msg[next+4:]),
+		}
+		length := int(binary.BigEndian.Uint16(msg[next+8:]))
+		start := next + 10
+		end := start + length
+		if end > len(msg) {
+			return nil, 0, errTruncatedMessage
+		}
+
+		switch record.Type {
+		case TypeNS, TypeCNAME:
+			record.Target, _, err = readName(msg, start)
+		case TypeMX:
+			if length < 3 {
+				return nil, 0, errTruncatedMessage
+			}
+			record.Preference = binary.BigEndian.Uint16(msg[start:])
+			record.Target, _, err = readName(msg, start+2)
+		case TypeA, TypeAAAA:
+			if length != net.IPv4len && length != net.IPv6len {
+				return nil, 0, fmt.Errorf("invalid address length %d", length)
+			}
+			record.Address = net.IP(append([]byte(nil), msg[start:end]...))
+		case TypeTXT:
+			record.Text, err = readText(msg[start:end])
+		case TypeSOA:
+			record.SOA, err = readSOA(msg, start, end)
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+
+		records = append(records, record)
+		offset = end
+	}
+	return records, offset, nil
+}
+
+// readSOA decodes the rdata of a SOA record.
+func readSOA(msg []byte, offset, end int) (*SOA, error) {
+	mname, offset, err := readName(msg, offset)
+	if err != nil {
+		return nil, err
+	}
+	rname, offset, err := readName(msg, offset)
+	if err != nil {
+		return nil, err
+	}
+	if offset+20 > end {
+		return nil, errTruncatedMessage
+	}
+
+	return &SOA{
+		MName:   mname,
+		RName:   rname,
+		Serial:  binary.BigEndian.Uint32(msg[offset:]),
+		Refresh: binary.BigEndian.Uint32(msg[offset+4:]),
+		Retry:   binary.BigEndian.Uint32(msg[offset+8:]),
+		Expire:  binary.BigEndian.Uint32(msg[offset+12:]),
+		Minimum: binary.BigEndian.Uint32(msg[offset+16:]),
+	}, nil
+}
+
+// readText joins the character strings of a TXT record. Long values such as
+// DKIM keys are split into strings of at most 255 bytes.
+func readText(rdata []byte) (string, error) {
+	var b strings.Builder
+	for len(rdata) > 0 {
+		length := int(rdata[0])
+		if 1+length > len(rdata) {
+			return "", errTruncatedMessage
+		}
+		b.Write(rdata[1 : 1+length])
+		rdata = rdata[1+length:]
+	}
+	return b.String(), nil
+}
+
+// readName decodes a possibly compressed name at offset. It returns the
+// name without the trailing dot and the offset following the name.
+func readName(msg []byte, offset int) (string, int, error) {
+	var labels []string
+	next := -1
+
+	// A name can have at most 127 labels, more jumps mean a pointer loop.
+	for jumps := 0; jumps < 128; jumps++ {
+		if offset >= len(msg) {
+			return "", 0, errTruncatedMessage
+		}
+
+		length := int(msg[offset])
+		switch {
+		case length == 0:
+			if next < 0 {
+				next = offset + 1
+			}
+			return strings.ToLower(strings.Join(labels, ".")), next, nil
+
+		case length&0xc0 == 0xc0:
+			if offset+1 >= len(msg) {
+				return "", 0, errTruncatedMessage
+			}
+			if next < 0 {
+				next = offset + 2
+			}
+			offset = int(binary.BigEndian.Uint16(msg[offset:]) & 0x3fff)
+
+		default:
+			if offset+1+length > len(msg) {
+				return "", 0, errTruncatedMessage
+			}
+			labels = append(labels, string(msg[offset+1:offset+1+length]))
+			offset += 1 + length
+		}
+	}
+
+	return "", 0, fmt.Errorf("too many compression pointers in DNS message")
+}
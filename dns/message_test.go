@@ -0,0 +1,200 @@
+package dns
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+// Saved responses, see the test using each for its content.
+const (
+	// example.com NS: three NS records whose targets are compressed, the
+	// second one pointing into the first (ns2 -> example.net) and the third
+	// one into the second (ns3 -> ns2.example.net).
+	nsResponse = "123481800001000300000000076578616d706c6503636f6d0000020001" +
+		"c00c000200010000012c0011036e7331076578616d706c65036e657400" +
+		"c00c000200010000012c0006036e7332c02d" +
+		"c00c000200010000012c0006036e7333c046"
+
+	// missing.example.com A: NXDOMAIN with the SOA of example.com in the
+	// authority section.
+	soaResponse = "432181830001000000010000076d697373696e67076578616d706c6503636f6d0000010001" +
+		"c0140006000100000e100027036e7331c0140a686f73746d6173746572c014" +
+		"78a48db500001c2000000e10001275000000012c"
+
+	// example.com MX: two MX records whose names are compressed, the second
+	// one pointing into the first (mx2 -> mail -> example.com), and an A
+	// record of mail.example.com.
+	mxResponse = "123481800001000300000000076578616d706c6503636f6d00000f0001" +
+		"c00c000f00010000012c0009000a046d61696cc00c" +
+		"c00c000f00010000012c00080014036d7832c02b" +
+		"c02b000100010000003c0004c0000219"
+
+	// sel._domainkey.example.com TXT: a DKIM key split into two strings.
+	txtResponse = "0007818000010001000000000373656c0a5f646f6d61696e6b6579076578616d706c6503636f6d0000100001" +
+		"c00c001000010000012c002012763d444b494d313b206b3d7273613b20703d0c4d494942496a414e42676b71"
+)
+
+func decode(t *testing.T, s string) []byte {
+	t.Helper()
+	msg, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestParseCompressedNames(t *testing.T) {
+	resp, err := parseResponse(decode(t, nsResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.ID != 0x1234 || resp.RCode != RCodeSuccess || resp.Truncated {
+		t.Errorf("header = %+v", resp)
+	}
+	if len(resp.Answers) != 3 {
+		t.Fatalf("got %d answers, want 3", len(resp.Answers))
+	}
+
+	for i, target := range []string{"ns1.example.net", "ns2.example.net", "ns3.ns2.example.net"} {
+		got := resp.Answers[i]
+		if got.Name != "example.com" || got.Type != TypeNS || got.TTL != 300 || got.Target != target {
+			t.Errorf("answer %d = %+v, want NS %s", i, got, target)
+		}
+	}
+}
+
+func TestParseMX(t *testing.T) {
+	resp, err := parseResponse(decode(t, mxResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.ID != 0x1234 || resp.RCode != RCodeSuccess || resp.Truncated {
+		t.Errorf("header = %+v", resp)
+	}
+	if len(resp.Answers) != 3 {
+		t.Fatalf("got %d answers, want 3", len(resp.Answers))
+	}
+
+	want := []Record{
+		{Name: "example.com", Type: TypeMX, TTL: 300, Preference: 10, Target: "mail.example.com"},
+		{Name: "example.com", Type: TypeMX, TTL: 300, Preference: 20, Target: "mx2.mail.example.com"},
+	}
+	for i, w := range want {
+		got := resp.Answers[i]
+		if got.Name != w.Name || got.Type != w.Type || got.TTL != w.TTL || got.Preference != w.Preference || got.Target != w.Target {
+			t.Errorf("answer %d = %+v, want %+v", i, got, w)
+		}
+	}
+
+	a := resp.Answers[2]
+	if a.Name != "mail.example.com" || a.Type != TypeA || a.Address.String() != "192.0.2.25" {
+		t.Errorf("A record = %+v", a)
+	}
+}
+
+func TestParseSOAInAuthority(t *testing.T) {
+	resp, err := parseResponse(decode(t, soaResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if resp.RCode != RCodeNXDomain {
+		t.Errorf("RCode = %d, want %d", resp.RCode, RCodeNXDomain)
+	}
+	if len(resp.Answers) != 0 || len(resp.Authority) != 1 {
+		t.Fatalf("got %d answers and %d authority records, want 0 and 1", len(resp.Answers), len(resp.Authority))
+	}
+
+	record := resp.Authority[0]
+	if record.Name != "example.com" || record.Type != TypeSOA || record.SOA == nil {
+		t.Fatalf("authority record = %+v", record)
+	}
+	want := SOA{
+		MName:   "ns1.example.com",
+		RName:   "hostmaster.example.com",
+		Serial:  2024050101,
+		Refresh: 7200,
+		Retry:   3600,
+		Expire:  1209600,
+		Minimum: 300,
+	}
+	if *record.SOA != want {
+		t.Errorf("SOA = %+v, want %+v", *record.SOA, want)
+	}
+}
+
+func TestParseSplitTXT(t *testing.T) {
+	resp, err := parseResponse(decode(t, txtResponse))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.Answers) != 1 {
+		t.Fatalf("got %d answers, want 1", len(resp.Answers))
+	}
+	if got := resp.Answers[0].Text; got != "v=DKIM1; k=rsa; p=MIIBIjANBgkq" {
+		t.Errorf("Text = %q", got)
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	for _, response := range []string{nsResponse, mxResponse, soaResponse, txtResponse} {
+		msg := decode(t, response)
+		for n := 0; n < len(msg); n++ {
+			if _, err := parseResponse(msg[:n]); err == nil {
+				t.Errorf("parsing the first %d of %d bytes succeeded, want an error", n, len(msg))
+			}
+		}
+	}
+}
+
+func TestParseTruncatedFlag(t *testing.T) {
+	msg := decode(t, nsResponse)
+	msg[2] |= 1 << 1 // TC
+	resp, err := parseResponse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !resp.Truncated {
+		t.Error("Truncated = false with the TC bit set")
+	}
+}
+
+func TestParsePointerLoop(t *testing.T) {
+	tests := map[string]string{
+		// The question name points at itself.
+		"self": "000181800001000000000000c00c00010001",
+		// Two pointers pointing at each other.
+		"cycle": "000181800001000000000000c00ec00c00010001",
+		// A label followed by a pointer back to the label.
+		"label": "0001818000010000000000000161c00c00010001",
+	}
+
+	for name, response := range tests {
+		_, err := parseResponse(decode(t, response))
+		if err == nil {
+			t.Errorf("%s: parsing succeeded, want an error", name)
+		}
+	}
+}
+
+func TestBuildQuery(t *testing.T) {
+	query, err := buildQuery(0xbeef, "Example.com.", TypeSOA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// "Example" keeps its case, the trailing dot is dropped.
+	want := "beef01000001000000000000074578616d706c6503636f6d0000060001"
+	if got := hex.EncodeToString(query); got != want {
+		t.Errorf("query = %s, want %s", got, want)
+	}
+
+	if _, err := buildQuery(1, "a..b", TypeNS); err == nil {
+		t.Error("building a query for an empty label succeeded")
+	}
+	if _, err := buildQuery(1, strings.Repeat("a", 64)+".com", TypeNS); err == nil {
+		t.Error("building a query for a 64 byte label succeeded")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/Server-Manager-cloud/cronjobs
+
+go 1.22
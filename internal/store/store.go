@@ -0,0 +1,70 @@
+// Package store persists small pieces of collector state, such as the
+// previous counter sample, between runs.
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Store keeps one JSON file per key in a directory.
+type Store struct {
+	Dir string
+}
+
+// path returns the file backing key.
+func (s Store) path(key string) string {
+	return filepath.Join(s.Dir, key+".json")
+}
+
+// Load decodes the value stored under key into v. It reports false when
+// nothing has been stored yet.
+func (s Store) Load(key string, v interface{}) (bool, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to read state %s: %v", key, err)
+	}
+
+	if err := json.Unmarshal(data, v); err != nil {
+		return false, fmt.Errorf("failed to parse state %s: %v", key, err)
+	}
+	return true, nil
+}
+
+// Save stores v under key. The file is replaced atomically so a crash never
+// leaves a half written state behind.
+func (s Store) Save(key string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to encode state %s: %v", key, err)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create state directory: %v", err)
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, key+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write state %s: %v", key, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state %s: %v", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write state %s: %v", key, err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		return fmt.Errorf("failed to write state %s: %v", key, err)
+	}
+	return nil
+}
@@ -1,44 +1,190 @@
 package main
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"os/exec"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/Server-Manager-cloud/cronjobs/collector"
+	"github.com/Server-Manager-cloud/cronjobs/config"
+	"github.com/Server-Manager-cloud/cronjobs/scheduler"
 )
 
+const usage = `Usage:
+  smc                    run the collectors that are due this minute (for cron)
+  smc run [collector...] run the given collectors now, or all of them
+  smc daemon             keep running and execute every collector on its schedule
+  smc list               list the available collectors`
+
 func main() {
-	// List of Go script paths to run
-	scripts := []string{
-		"bin/harddrive.go",
-		"bin/cpu.go",
-		"bin/domains.go",
-		"bin/nameserver.go",
-		"bin/os.go",
+	mode := ""
+	var args []string
+	if len(os.Args) > 1 {
+		mode, args = os.Args[1], os.Args[2:]
+	}
+
+	if mode == "list" {
+		for _, c := range collector.All() {
+			fmt.Println(c.Name())
+		}
+		return
+	}
+
+	// Load environment variables from the .env file
+	err := config.LoadEnv(".env")
+	if err != nil {
+		log.Fatalf("Error loading .env file: %v", err)
+	}
+
+	// Load configuration from smc.json
+	cfg, err := config.Load("smc.json")
+	if err != nil {
+		log.Fatalf("Error loading smc.json file: %v", err)
+	}
+
+	env, err := collector.NewEnv(cfg)
+	if err != nil {
+		log.Fatalf("Error setting up collectors: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	switch mode {
+	case "":
+		runDue(ctx, env, time.Now())
+	case "run":
+		collectors, err := selectCollectors(args)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if !runCollectors(ctx, env, collectors) {
+			os.Exit(1)
+		}
+	case "daemon":
+		runDaemon(ctx, env)
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		os.Exit(2)
+	}
+}
+
+// selectCollectors resolves collector names given on the command line. No
+// names selects every collector.
+func selectCollectors(names []string) ([]collector.Collector, error) {
+	if len(names) == 0 {
+		return collector.All(), nil
+	}
+
+	var collectors []collector.Collector
+	for _, name := range names {
+		c, ok := collector.Get(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown collector %q, see `smc list`", name)
+		}
+		collectors = append(collectors, c)
 	}
+	return collectors, nil
+}
 
-	// Iterate through the scripts and run each
-	for _, scriptPath := range scripts {
-		fmt.Printf("Running script: %s\n", scriptPath)
+// runCollectors runs the collectors one after another and reports whether
+// all of them succeeded. The records they create are sent together in a
+// batch at the end.
+func runCollectors(ctx context.Context, env *collector.Env, collectors []collector.Collector) bool {
+	batch := env.Batch()
 
-		// Command to execute the script
-		cmd := exec.Command("go", "run", scriptPath)
+	ok := true
+	for _, c := range collectors {
+		log.Printf("Running collector: %s", c.Name())
 
-		// Capture the script's output
-		var stdout, stderr bytes.Buffer
-		cmd.Stdout = &stdout
-		cmd.Stderr = &stderr
+		if err := collector.Run(ctx, batch, c); err != nil {
+			log.Print(err)
+			ok = false
+		}
+	}
 
-		// Run the command
-		err := cmd.Run()
+	if err := batch.Flush(ctx); err != nil {
+		log.Printf("Error sending records: %v", err)
+		ok = false
+	}
+	return ok
+}
+
+// runDue runs every collector with an activation since its previous run.
+// The activation each collector last ran for is kept in the state dir, so
+// no run is skipped when cron starts the agent late or not at all for a
+// while. A collector that never ran runs when it is due this minute.
+func runDue(ctx context.Context, env *collector.Env, now time.Time) {
+	var last map[string]time.Time
+	if _, err := env.State.Load("schedule", &last); err != nil {
+		log.Printf("Ignoring previous activations: %v", err)
+	}
+	if last == nil {
+		last = map[string]time.Time{}
+	}
+
+	var due []collector.Collector
+	for _, c := range collector.All() {
+		schedule, err := scheduler.Parse(env.Config.Collector(c.Name()).Schedule)
 		if err != nil {
-			log.Printf("Error running script %s: %v\nStderr: %s\n", scriptPath, err, stderr.String())
+			log.Printf("Error in schedule of %s: %v", c.Name(), err)
 			continue
 		}
 
-		// Print the script's output
-		fmt.Printf("Output from %s:\n%s\n", scriptPath, stdout.String())
+		previous, found := last[c.Name()]
+		if !found || previous.After(now) {
+			previous = now.Truncate(time.Minute).Add(-time.Nanosecond)
+			last[c.Name()] = previous
+		}
+
+		if activation := scheduler.Missed(schedule, previous, now); !activation.IsZero() {
+			if !scheduler.Due(schedule, now) {
+				log.Printf("Running %s for the missed activation at %s", c.Name(), activation.Format(time.RFC3339))
+			}
+			last[c.Name()] = activation
+			due = append(due, c)
+		}
+	}
+
+	// Saved before running, so a run overlapping this one does not run the
+	// same activations again.
+	if err := env.State.Save("schedule", last); err != nil {
+		log.Printf("Error saving activations: %v", err)
+	}
+
+	runCollectors(ctx, env, due)
+	log.Println("All due collectors executed.")
+}
+
+// runDaemon runs every collector on its own schedule until ctx is cancelled.
+func runDaemon(ctx context.Context, env *collector.Env) {
+	var jobs []scheduler.Job
+	for _, c := range collector.All() {
+		settings := env.Config.Collector(c.Name())
+		schedule, err := scheduler.Parse(settings.Schedule)
+		if err != nil {
+			log.Fatalf("Error in schedule of %s: %v", c.Name(), err)
+		}
+
+		c := c
+		jobs = append(jobs, scheduler.Job{
+			Name:     c.Name(),
+			Schedule: schedule,
+			Jitter:   time.Duration(settings.Jitter),
+			Run: func(ctx context.Context) error {
+				batch := env.Batch()
+				err := collector.Run(ctx, batch, c)
+				return errors.Join(err, batch.Flush(ctx))
+			},
+		})
+		log.Printf("Scheduled %s with %q", c.Name(), settings.Schedule)
 	}
 
-	fmt.Println("All scripts executed.")
+	scheduler.Run(ctx, jobs)
+	log.Println("Daemon stopped.")
 }
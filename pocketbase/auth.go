@@ -0,0 +1,205 @@
+package pocketbase
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SuperusersCollection is the auth collection holding PocketBase superusers.
+const SuperusersCollection = "_superusers"
+
+// legacyAdminsCollection selects the pre-0.23 /api/admins endpoints.
+const legacyAdminsCollection = "admins"
+
+// refreshWindow is how long before expiry a cached token gets refreshed.
+const refreshWindow = 5 * time.Minute
+
+// Authenticator supplies the token sent in the Authorization header.
+type Authenticator interface {
+	// Token returns a valid token, logging in or refreshing when needed.
+	Token(ctx context.Context, c *Client) (string, error)
+
+	// Invalidate drops the cached token after PocketBase rejected it.
+	Invalidate()
+}
+
+// StaticToken authenticates with a long-lived API token, e.g. a superuser
+// impersonation token stored in .env.
+type StaticToken string
+
+// Token returns the static token.
+func (t StaticToken) Token(ctx context.Context, c *Client) (string, error) {
+	return string(t), nil
+}
+
+// Invalidate is a no-op, a static token cannot be renewed.
+func (t StaticToken) Invalidate() {}
+
+// PasswordAuth logs in to an auth collection with an identity and password
+// and caches the resulting token until it is about to expire.
+type PasswordAuth struct {
+	// Collection is the auth collection to log in to. Defaults to
+	// SuperusersCollection; "admins" uses the legacy admin API.
+	Collection string
+	Identity   string
+	Password   string
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// authResponse is the part of an auth-with-password or auth-refresh
+// response we care about.
+type authResponse struct {
+	Token string `json:"token"`
+}
+
+// Token returns the cached token, refreshing it shortly before it expires
+// and logging in again when there is none or the refresh fails.
+func (a *PasswordAuth) Token(ctx context.Context, c *Client) (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	now := time.Now()
+	if a.token != "" && now.Before(a.expires.Add(-refreshWindow)) {
+		return a.token, nil
+	}
+
+	if a.token != "" && now.Before(a.expires) {
+		if err := a.refresh(ctx, c); err == nil {
+			return a.token, nil
+		}
+	}
+
+	if err := a.login(ctx, c); err != nil {
+		return "", err
+	}
+	return a.token, nil
+}
+
+// Invalidate forgets the cached token so the next request logs in again.
+func (a *PasswordAuth) Invalidate() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.token = ""
+	a.expires = time.Time{}
+}
+
+// authPath returns the path of an auth endpoint (auth-with-password or
+// auth-refresh) of the configured collection.
+func (a *PasswordAuth) authPath(action string) string {
+	collection := a.Collection
+	if collection == "" {
+		collection = SuperusersCollection
+	}
+	if collection == legacyAdminsCollection {
+		return "/api/admins/" + action
+	}
+	return fmt.Sprintf("/api/collections/%s/%s", url.PathEscape(collection), action)
+}
+
+func (a *PasswordAuth) login(ctx context.Context, c *Client) error {
+	payload := map[string]string{
+		"identity": a.Identity,
+		"password": a.Password,
+	}
+
+	var resp authResponse
+	err := c.send(ctx, http.MethodPost, a.authPath("auth-with-password"), nil, payload, "", &resp)
+	if err != nil {
+		return fmt.Errorf("failed to log in as %s: %w", a.Identity, err)
+	}
+
+	return a.store(resp.Token)
+}
+
+func (a *PasswordAuth) refresh(ctx context.Context, c *Client) error {
+	var resp authResponse
+	err := c.send(ctx, http.MethodPost, a.authPath("auth-refresh"), nil, nil, a.token, &resp)
+	if err != nil {
+		return fmt.Errorf("failed to refresh token: %w", err)
+	}
+
+	return a.store(resp.Token)
+}
+
+func (a *PasswordAuth) store(token string) error {
+	if token == "" {
+		return fmt.Errorf("auth response contains no token")
+	}
+
+	expires, err := tokenExpiry(token)
+	if err != nil {
+		return err
+	}
+
+	a.token = token
+	a.expires = expires
+	return nil
+}
+
+// tokenExpiry reads the exp claim of a PocketBase JWT.
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, fmt.Errorf("token is not a JWT")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to decode token payload: %v", err)
+	}
+
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse token claims: %v", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, fmt.Errorf("token has no expiry")
+	}
+
+	return time.Unix(claims.Exp, 0), nil
+}
+
+// AuthFromEnv builds an Authenticator from the environment (normally loaded
+// from .env):
+//
+//	PB_TOKEN            long-lived API token, takes precedence
+//	PB_IDENTITY         email or username to log in with
+//	PB_PASSWORD         password for PB_IDENTITY
+//	PB_AUTH_COLLECTION  auth collection, defaults to _superusers
+//
+// It returns nil when no credentials are configured, in which case requests
+// are sent unauthenticated.
+func AuthFromEnv() (Authenticator, error) {
+	if token := os.Getenv("PB_TOKEN"); token != "" {
+		return StaticToken(token), nil
+	}
+
+	identity := os.Getenv("PB_IDENTITY")
+	password := os.Getenv("PB_PASSWORD")
+	if identity == "" && password == "" {
+		return nil, nil
+	}
+	if identity == "" || password == "" {
+		return nil, fmt.Errorf("both PB_IDENTITY and PB_PASSWORD must be set")
+	}
+
+	return &PasswordAuth{
+		Collection: os.Getenv("PB_AUTH_COLLECTION"),
+		Identity:   identity,
+		Password:   password,
+	}, nil
+}
@@ -0,0 +1,70 @@
+package pocketbase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// MaxBatchRequests is the number of requests PocketBase accepts in a single
+// batch by default.
+const MaxBatchRequests = 50
+
+// BatchRequest is a single request of a batch.
+type BatchRequest struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   interface{} `json:"body,omitempty"`
+}
+
+// BatchResult is the response to a single request of a batch.
+type BatchResult struct {
+	Status int             `json:"status"`
+	Body   json.RawMessage `json:"body"`
+}
+
+// CreateRequest returns the batch request creating a record in collection.
+func CreateRequest(collection string, body interface{}) BatchRequest {
+	return BatchRequest{Method: http.MethodPost, URL: recordsPath(collection, ""), Body: body}
+}
+
+// UpdateRequest returns the batch request updating the record id of
+// collection.
+func UpdateRequest(collection, id string, body interface{}) BatchRequest {
+	return BatchRequest{Method: http.MethodPatch, URL: recordsPath(collection, id), Body: body}
+}
+
+// DeleteRequest returns the batch request deleting the record id of
+// collection.
+func DeleteRequest(collection, id string) BatchRequest {
+	return BatchRequest{Method: http.MethodDelete, URL: recordsPath(collection, id)}
+}
+
+// Send sends a single request of a batch on its own, for servers without
+// the batch API.
+func (c *Client) Send(ctx context.Context, request BatchRequest) error {
+	return c.do(ctx, request.Method, request.URL, nil, request.Body, nil)
+}
+
+// Batch sends requests to /api/batch, where PocketBase runs them in a single
+// transaction: either all of them succeed or none. The batch API needs
+// PocketBase 0.23 or later and must be enabled in its settings, see
+// IsBatchUnsupported.
+func (c *Client) Batch(ctx context.Context, requests []BatchRequest) ([]BatchResult, error) {
+	body := map[string]interface{}{"requests": requests}
+
+	var results []BatchResult
+	if err := c.do(ctx, http.MethodPost, "/api/batch", nil, body, &results); err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// IsBatchUnsupported reports whether err means the batch API is not
+// available: 404 from PocketBase before 0.23, 403 when batch requests are
+// disabled in the settings.
+func IsBatchUnsupported(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusForbidden)
+}
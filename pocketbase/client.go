@@ -0,0 +1,185 @@
+// Package pocketbase is a small client for the PocketBase records API that
+// all collectors use to submit their data.
+package pocketbase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Client talks to a single PocketBase instance.
+type Client struct {
+	// BaseURL is the root of the PocketBase instance, e.g.
+	// https://admin.server-manager.cloud
+	BaseURL string
+
+	// HTTPClient is used for every request. http.DefaultClient is used when nil.
+	HTTPClient *http.Client
+
+	// Auth authenticates requests. Requests are sent anonymously when nil.
+	Auth Authenticator
+
+	// Timeout bounds every single attempt of a request. DefaultTimeout is
+	// used when zero.
+	Timeout time.Duration
+
+	// Retry controls how requests failing with a retryable error are
+	// repeated. The zero value sends every request once.
+	Retry RetryPolicy
+
+	// Breaker stops sending requests while the server keeps failing. No
+	// breaker is used when nil.
+	Breaker *Breaker
+}
+
+// NewClient creates a client for the given PocketBase domain or URL. A bare
+// domain such as the one stored in smc.json is served over https.
+func NewClient(domain string) *Client {
+	baseURL := strings.TrimRight(domain, "/")
+	if !strings.Contains(baseURL, "://") {
+		baseURL = "https://" + baseURL
+	}
+
+	return &Client{
+		BaseURL:    baseURL,
+		HTTPClient: &http.Client{},
+		Timeout:    DefaultTimeout,
+		Retry:      DefaultRetryPolicy,
+		Breaker:    NewBreaker(5, time.Minute),
+	}
+}
+
+// recordsPath returns the API path of a collection's records, optionally
+// followed by a record ID.
+func recordsPath(collection, id string) string {
+	path := fmt.Sprintf("/api/collections/%s/records", url.PathEscape(collection))
+	if id != "" {
+		path += "/" + url.PathEscape(id)
+	}
+	return path
+}
+
+// do sends an authenticated request to PocketBase. A 401 response drops the
+// cached token and the request is retried once with a fresh one.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	if c.Auth == nil {
+		return c.send(ctx, method, path, query, body, "", out)
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, err := c.Auth.Token(ctx, c)
+		if err != nil {
+			return fmt.Errorf("failed to authenticate: %w", err)
+		}
+
+		err = c.send(ctx, method, path, query, body, token, out)
+		if attempt == 0 && IsUnauthorized(err) {
+			c.Auth.Invalidate()
+			continue
+		}
+		return err
+	}
+}
+
+// send sends a request to PocketBase, retrying it according to c.Retry and
+// guarded by c.Breaker.
+func (c *Client) send(ctx context.Context, method, path string, query url.Values, body interface{}, token string, out interface{}) error {
+	for attempt := 1; ; attempt++ {
+		if c.Breaker != nil {
+			if err := c.Breaker.allow(); err != nil {
+				return err
+			}
+		}
+
+		err := c.sendOnce(ctx, method, path, query, body, token, out)
+		if c.Breaker != nil {
+			// A cancelled request says nothing about the server
+			if ctx.Err() != nil {
+				c.Breaker.release()
+			} else {
+				c.Breaker.record(err)
+			}
+		}
+		if err == nil || !IsRetryable(err) || attempt >= c.Retry.MaxAttempts || ctx.Err() != nil {
+			return err
+		}
+
+		delay, ok := c.Retry.delay(attempt, err)
+		if !ok {
+			return err
+		}
+		if sleepErr := sleep(ctx, delay); sleepErr != nil {
+			return err
+		}
+	}
+}
+
+// sendOnce sends a request to PocketBase and decodes the JSON response into
+// out (when out is not nil). Any non-2xx response is returned as an
+// *APIError.
+func (c *Client) sendOnce(ctx context.Context, method, path string, query url.Values, body interface{}, token string, out interface{}) error {
+	timeout := c.Timeout
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	endpoint := c.BaseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	var reqBody io.Reader
+	if body != nil {
+		payloadBytes, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to marshal payload: %v", err)
+		}
+		reqBody = bytes.NewReader(payloadBytes)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reqBody)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %v", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return decodeAPIError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package pocketbase
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// FieldError describes why PocketBase rejected a single record field.
+type FieldError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// APIError is the decoded error body PocketBase returns for non-2xx responses.
+type APIError struct {
+	Status  int                   `json:"status"`
+	Message string                `json:"message"`
+	Data    map[string]FieldError `json:"data"`
+
+	// RetryAfter is the wait the server asked for with a Retry-After
+	// header, zero without one.
+	RetryAfter time.Duration `json:"-"`
+}
+
+func (e *APIError) Error() string {
+	msg := fmt.Sprintf("pocketbase: HTTP %d", e.Status)
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+
+	if len(e.Data) > 0 {
+		fields := make([]string, 0, len(e.Data))
+		for field := range e.Data {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+
+		details := make([]string, 0, len(fields))
+		for _, field := range fields {
+			details = append(details, fmt.Sprintf("%s: %s", field, e.Data[field].Message))
+		}
+		msg += " (" + strings.Join(details, "; ") + ")"
+	}
+
+	return msg
+}
+
+// IsNotFound reports whether err is a PocketBase 404 response.
+func IsNotFound(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound
+}
+
+// IsUnauthorized reports whether err is a PocketBase 401 response.
+func IsUnauthorized(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Status == http.StatusUnauthorized
+}
+
+// IsNotUnique reports whether err is a PocketBase 400 response rejecting
+// field because another record has the same value.
+func IsNotUnique(err error, field string) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadRequest {
+		return false
+	}
+	return apiErr.Data[field].Code == "validation_not_unique"
+}
+
+// IsRetryable reports whether a request that failed with err may succeed
+// when sent again later: network errors, timeouts, an open circuit breaker
+// and 408, 429 and 5xx responses. Other PocketBase responses, e.g. a
+// rejected field, fail again, and so do errors building the request or
+// decoding a successful response, which must not be sent twice. So do an
+// invalid URL and a certificate that does not verify.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status == http.StatusRequestTimeout ||
+			apiErr.Status == http.StatusTooManyRequests ||
+			apiErr.Status >= 500
+	}
+
+	var verifyErr *tls.CertificateVerificationError
+	var authorityErr x509.UnknownAuthorityError
+	var hostnameErr x509.HostnameError
+	var invalidErr x509.CertificateInvalidError
+	if errors.As(err, &verifyErr) || errors.As(err, &authorityErr) ||
+		errors.As(err, &hostnameErr) || errors.As(err, &invalidErr) {
+		return false
+	}
+
+	// Every error of http.Client is a *url.Error, which is a net.Error even
+	// for a URL with an unsupported scheme, so only the error it wraps counts
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, context.DeadlineExceeded) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, ErrCircuitOpen)
+}
+
+// decodeAPIError builds an *APIError from a failed response. Bodies that are
+// not PocketBase JSON errors (e.g. a proxy's HTML page) fall back to the HTTP
+// status text.
+func decodeAPIError(resp *http.Response) error {
+	apiErr := &APIError{Status: resp.StatusCode}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err == nil && len(body) > 0 {
+		// A partially decoded body is still more useful than nothing.
+		_ = json.Unmarshal(body, apiErr)
+	}
+
+	// Some PocketBase versions omit the status in the body.
+	apiErr.Status = resp.StatusCode
+	apiErr.RetryAfter = parseRetryAfter(resp.Header, time.Now())
+	if apiErr.Message == "" {
+		apiErr.Message = http.StatusText(resp.StatusCode)
+	}
+
+	return apiErr
+}
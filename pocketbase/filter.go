@@ -0,0 +1,70 @@
+package pocketbase
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// filterPlaceholder matches a {:name} placeholder in a filter expression.
+var filterPlaceholder = regexp.MustCompile(`\{:(\w+)\}`)
+
+// Filter builds a filter expression by replacing the {:name} placeholders
+// in expr with the escaped values of params, like pb.filter() of the
+// PocketBase SDKs:
+//
+//	Filter("server = {:server} && name = {:name}", map[string]interface{}{
+//		"server": serverID,
+//		"name":   domain,
+//	})
+//
+// Placeholders without a value are left unchanged.
+func Filter(expr string, params map[string]interface{}) string {
+	return filterPlaceholder.ReplaceAllStringFunc(expr, func(placeholder string) string {
+		value, ok := params[placeholder[2:len(placeholder)-1]]
+		if !ok {
+			return placeholder
+		}
+		return filterValue(value)
+	})
+}
+
+// filterValue formats value as a filter literal. Strings are single quoted
+// with embedded backslashes and quotes escaped.
+func filterValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return quoteFilter(v)
+	case bool:
+		return strconv.FormatBool(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return fmt.Sprint(v)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case time.Time:
+		return quoteFilter(v.UTC().Format("2006-01-02 15:04:05.000Z"))
+	case fmt.Stringer:
+		return quoteFilter(v.String())
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return quoteFilter(fmt.Sprint(v))
+		}
+		return quoteFilter(string(data))
+	}
+}
+
+// filterEscaper escapes backslashes before quotes, so a value ending in a
+// backslash cannot escape the closing quote.
+var filterEscaper = strings.NewReplacer(`\`, `\\`, "'", `\'`)
+
+func quoteFilter(s string) string {
+	return "'" + filterEscaper.Replace(s) + "'"
+}
@@ -0,0 +1,37 @@
+package pocketbase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		value interface{}
+		want  string
+	}{
+		{"example.com", `name = 'example.com'`},
+		{"it's", `name = 'it\'s'`},
+		{`a\`, `name = 'a\\'`},
+		{`a\' || id != '`, `name = 'a\\\' || id != \''`},
+		{nil, `name = null`},
+		{true, `name = true`},
+		{42, `name = 42`},
+		{1.5, `name = 1.5`},
+		{time.Date(2024, 5, 1, 12, 0, 0, 0, time.FixedZone("CEST", 2*60*60)), `name = '2024-05-01 10:00:00.000Z'`},
+		{[]string{"a"}, `name = '["a"]'`},
+	}
+
+	for _, tt := range tests {
+		if got := Filter("name = {:name}", map[string]interface{}{"name": tt.value}); got != tt.want {
+			t.Errorf("Filter(%#v) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestFilterMissingParam(t *testing.T) {
+	got := Filter("server = {:server} && name = {:name}", map[string]interface{}{"server": "abc"})
+	if want := `server = 'abc' && name = {:name}`; got != want {
+		t.Errorf("Filter = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,174 @@
+package pocketbase
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// DefaultPerPage is the page size used by ListAll.
+const DefaultPerPage = 200
+
+// ListOptions controls a single records list request.
+type ListOptions struct {
+	Page      int
+	PerPage   int
+	Filter    string
+	Sort      string
+	Fields    string
+	SkipTotal bool
+}
+
+// query converts the options to PocketBase query parameters.
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.Page > 0 {
+		query.Set("page", strconv.Itoa(o.Page))
+	}
+	if o.PerPage > 0 {
+		query.Set("perPage", strconv.Itoa(o.PerPage))
+	}
+	if o.Filter != "" {
+		query.Set("filter", o.Filter)
+	}
+	if o.Sort != "" {
+		query.Set("sort", o.Sort)
+	}
+	if o.Fields != "" {
+		query.Set("fields", o.Fields)
+	}
+	if o.SkipTotal {
+		query.Set("skipTotal", "1")
+	}
+	return query
+}
+
+// ListResult is one page of records returned by PocketBase.
+type ListResult[T any] struct {
+	Page       int `json:"page"`
+	PerPage    int `json:"perPage"`
+	TotalItems int `json:"totalItems"`
+	TotalPages int `json:"totalPages"`
+	Items      []T `json:"items"`
+}
+
+// Create inserts a new record into collection. The created record is decoded
+// into out when out is not nil.
+func (c *Client) Create(ctx context.Context, collection string, body, out interface{}) error {
+	return c.do(ctx, http.MethodPost, recordsPath(collection, ""), nil, body, out)
+}
+
+// Update changes the given fields of an existing record. PocketBase updates
+// are partial, so fields missing from body are left untouched.
+func (c *Client) Update(ctx context.Context, collection, id string, body, out interface{}) error {
+	return c.do(ctx, http.MethodPatch, recordsPath(collection, id), nil, body, out)
+}
+
+// Delete removes a record from collection.
+func (c *Client) Delete(ctx context.Context, collection, id string) error {
+	return c.do(ctx, http.MethodDelete, recordsPath(collection, id), nil, nil, nil)
+}
+
+// Upsert updates the first record of collection matching filter, or creates
+// a record when none matches. The record is created with id, unless it is
+// empty, so that an upsert sent again after its create was committed, e.g.
+// after a timeout, updates that record instead of adding another one.
+func (c *Client) Upsert(ctx context.Context, collection, filter, id string, body, out interface{}) error {
+	existing, err := FirstByFilter[struct {
+		ID string `json:"id"`
+	}](ctx, c, collection, filter)
+	if err != nil {
+		return err
+	}
+
+	if existing != nil {
+		return c.Update(ctx, collection, existing.ID, body, out)
+	}
+	if id == "" {
+		return c.Create(ctx, collection, body, out)
+	}
+
+	record, err := withID(body, id)
+	if err != nil {
+		return err
+	}
+	err = c.Create(ctx, collection, record, out)
+	if IsNotUnique(err, "id") {
+		return c.Update(ctx, collection, id, body, out)
+	}
+	return err
+}
+
+// withID returns the fields of body, which must encode to a JSON object,
+// with the record ID set to id.
+func withID(body interface{}, id string) (map[string]json.RawMessage, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode request body: %v", err)
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil || record == nil {
+		return nil, fmt.Errorf("request body is not a JSON object")
+	}
+	record["id"], _ = json.Marshal(id)
+	return record, nil
+}
+
+// GetOne fetches a single record by ID and decodes it into out.
+func (c *Client) GetOne(ctx context.Context, collection, id string, out interface{}) error {
+	return c.do(ctx, http.MethodGet, recordsPath(collection, id), nil, nil, out)
+}
+
+// List fetches a single page of records from collection.
+func List[T any](ctx context.Context, c *Client, collection string, opts ListOptions) (*ListResult[T], error) {
+	var result ListResult[T]
+	err := c.do(ctx, http.MethodGet, recordsPath(collection, ""), opts.query(), nil, &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// ListAll fetches every page of records matching opts. opts.Page is ignored.
+func ListAll[T any](ctx context.Context, c *Client, collection string, opts ListOptions) ([]T, error) {
+	if opts.PerPage <= 0 {
+		opts.PerPage = DefaultPerPage
+	}
+	opts.SkipTotal = false
+
+	var items []T
+	for page := 1; ; page++ {
+		opts.Page = page
+		result, err := List[T](ctx, c, collection, opts)
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, result.Items...)
+		if page >= result.TotalPages || len(result.Items) == 0 {
+			return items, nil
+		}
+	}
+}
+
+// FirstByFilter returns the first record matching filter, or nil when no
+// record matches.
+func FirstByFilter[T any](ctx context.Context, c *Client, collection, filter string) (*T, error) {
+	result, err := List[T](ctx, c, collection, ListOptions{
+		Page:      1,
+		PerPage:   1,
+		Filter:    filter,
+		SkipTotal: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(result.Items) == 0 {
+		return nil, nil
+	}
+	return &result.Items[0], nil
+}
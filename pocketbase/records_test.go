@@ -0,0 +1,70 @@
+package pocketbase
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// TestUpsertCreatesWithID sends an upsert whose create is committed but
+// answered with a 503, as after a timeout. The retry is rejected for the
+// duplicate ID, and the record created by the first attempt is updated.
+func TestUpsertCreatesWithID(t *testing.T) {
+	recordSleeps(t)
+
+	var mu sync.Mutex
+	var requests []string
+	created := map[string]bool{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet:
+			w.Write([]byte(`{"page": 1, "perPage": 1, "items": []}`))
+		case r.Method == http.MethodPost && created[body["id"].(string)]:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status": 400, "message": "Failed to create record.", "data": {"id": {"code": "validation_not_unique", "message": "Value must be unique."}}}`))
+		case r.Method == http.MethodPost:
+			created[body["id"].(string)] = true
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status": 503, "message": "Timeout."}`))
+		default:
+			if _, ok := body["id"]; ok {
+				t.Errorf("update body %v has an ID", body)
+			}
+			w.Write([]byte(`{}`))
+		}
+	}))
+	defer server.Close()
+
+	body := struct {
+		Kernel string `json:"kernel"`
+	}{Kernel: "6.8"}
+	err := newTestClient(server.URL).Upsert(context.Background(), "server_os", "server = 'abc'", "os0000000000001", body, nil)
+	if err != nil {
+		t.Fatalf("Upsert failed: %v", err)
+	}
+
+	want := []string{
+		"GET /api/collections/server_os/records",
+		"POST /api/collections/server_os/records",
+		"POST /api/collections/server_os/records",
+		"PATCH /api/collections/server_os/records/os0000000000001",
+	}
+	if !reflect.DeepEqual(requests, want) {
+		t.Errorf("requests = %v, want %v", requests, want)
+	}
+	if len(created) != 1 || !created["os0000000000001"] {
+		t.Errorf("created %v, want only os0000000000001", created)
+	}
+}
@@ -0,0 +1,165 @@
+package pocketbase
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout bounds a single request when Client.Timeout is zero.
+const DefaultTimeout = 30 * time.Second
+
+// RetryPolicy controls how requests that failed with a retryable error (see
+// IsRetryable) are repeated.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one. Values
+	// below 2 disable retries.
+	MaxAttempts int
+
+	// BaseDelay is the delay before the first retry. It doubles with every
+	// further retry up to MaxDelay, and each delay is randomised by up to
+	// half so several agents do not retry in lockstep.
+	BaseDelay time.Duration
+	MaxDelay  time.Duration
+}
+
+// DefaultRetryPolicy is used by clients created with NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   time.Second,
+	MaxDelay:    30 * time.Second,
+}
+
+// delay returns the wait before retry number retry (starting at 1). A
+// Retry-After of the server is used instead when there is one. It reports
+// false when the server asks to wait longer than MaxDelay.
+func (p RetryPolicy) delay(retry int, err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.RetryAfter > 0 {
+		return apiErr.RetryAfter, p.MaxDelay <= 0 || apiErr.RetryAfter <= p.MaxDelay
+	}
+
+	if p.BaseDelay <= 0 {
+		return 0, true
+	}
+	shift := min(retry-1, 20)
+	delay := p.BaseDelay << shift
+	if delay>>shift != p.BaseDelay || delay <= 0 {
+		// The shift overflowed
+		delay = time.Duration(math.MaxInt64)
+	}
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)), true
+}
+
+// parseRetryAfter reads the Retry-After header, given in seconds or as an
+// HTTP date.
+func parseRetryAfter(header http.Header, now time.Time) time.Duration {
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(now) {
+		return date.Sub(now)
+	}
+	return 0
+}
+
+// sleep waits for d or until ctx is done. It is a variable so tests can
+// replace it.
+var sleep = func(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// ErrCircuitOpen is returned without sending a request while the circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("pocketbase: circuit breaker open, server considered down")
+
+// Breaker stops requests to a PocketBase instance that keeps failing. After
+// Threshold consecutive retryable failures it opens and rejects requests
+// with ErrCircuitOpen for Cooldown. Then a single trial request is let
+// through, which closes the breaker when it succeeds and opens it again when
+// it fails.
+type Breaker struct {
+	Threshold int
+	Cooldown  time.Duration
+
+	// now returns the current time, replaced in tests.
+	now func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+// NewBreaker returns a breaker with the given threshold and cooldown.
+func NewBreaker(threshold int, cooldown time.Duration) *Breaker {
+	return &Breaker{Threshold: threshold, Cooldown: cooldown}
+}
+
+func (b *Breaker) clock() time.Time {
+	if b.now != nil {
+		return b.now()
+	}
+	return time.Now()
+}
+
+// allow reports ErrCircuitOpen when no request may be sent now.
+func (b *Breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.Threshold <= 0 || b.failures < b.Threshold {
+		return nil
+	}
+	if b.clock().Before(b.openUntil) || b.trial {
+		return ErrCircuitOpen
+	}
+	b.trial = true
+	return nil
+}
+
+// record updates the breaker with the outcome of a request. Only retryable
+// errors count as failures, any other response shows the server is up.
+func (b *Breaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+	if !IsRetryable(err) {
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.Threshold > 0 && b.failures >= b.Threshold {
+		b.openUntil = b.clock().Add(b.Cooldown)
+	}
+}
+
+// release ends a request without counting its outcome, so a cancelled trial
+// request does not keep the breaker open.
+func (b *Breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
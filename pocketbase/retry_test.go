@@ -0,0 +1,375 @@
+package pocketbase
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testServer answers every request with the next status of statuses, the
+// last one repeated. It counts the requests it received.
+type testServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	statuses []int
+	header   http.Header
+	requests int
+}
+
+func newTestServer(t *testing.T, statuses ...int) *testServer {
+	t.Helper()
+
+	s := &testServer{statuses: statuses, header: http.Header{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		status := s.statuses[min(s.requests, len(s.statuses)-1)]
+		s.requests++
+		for name, values := range s.header {
+			w.Header()[name] = values
+		}
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if status >= 300 {
+			fmt.Fprintf(w, `{"status": %d, "message": %q, "data": {}}`, status, http.StatusText(status))
+			return
+		}
+		w.Write([]byte(`{"id": "abc"}`))
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testServer) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// recordSleeps replaces sleep with a function recording the delays instead
+// of waiting.
+func recordSleeps(t *testing.T) *[]time.Duration {
+	t.Helper()
+
+	var slept []time.Duration
+	original := sleep
+	sleep = func(ctx context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return ctx.Err()
+	}
+	t.Cleanup(func() { sleep = original })
+	return &slept
+}
+
+func newTestClient(url string) *Client {
+	client := NewClient(url)
+	client.Breaker = nil
+	return client
+}
+
+func TestRetryServerError(t *testing.T) {
+	slept := recordSleeps(t)
+	server := newTestServer(t, http.StatusServiceUnavailable, http.StatusOK)
+	client := newTestClient(server.URL)
+
+	if err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if server.count() != 2 {
+		t.Errorf("server got %d requests, want 2", server.count())
+	}
+	if len(*slept) != 1 {
+		t.Fatalf("slept %d times, want once", len(*slept))
+	}
+	if d := (*slept)[0]; d < DefaultRetryPolicy.BaseDelay/2 || d > DefaultRetryPolicy.BaseDelay {
+		t.Errorf("slept %s, want between half and all of %s", d, DefaultRetryPolicy.BaseDelay)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	slept := recordSleeps(t)
+	server := newTestServer(t, http.StatusBadGateway)
+	client := newTestClient(server.URL)
+
+	err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Status != http.StatusBadGateway {
+		t.Fatalf("error = %v, want HTTP 502", err)
+	}
+	if server.count() != DefaultRetryPolicy.MaxAttempts {
+		t.Errorf("server got %d requests, want %d", server.count(), DefaultRetryPolicy.MaxAttempts)
+	}
+	if len(*slept) != 2 || (*slept)[1] < DefaultRetryPolicy.BaseDelay {
+		t.Errorf("slept %v, want two growing delays", *slept)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	slept := recordSleeps(t)
+	server := newTestServer(t, http.StatusTooManyRequests, http.StatusOK)
+	server.header.Set("Retry-After", "7")
+	client := newTestClient(server.URL)
+
+	if err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil); err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != 7*time.Second {
+		t.Errorf("slept %v, want the 7s asked for", *slept)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	slept := recordSleeps(t)
+	server := newTestServer(t, http.StatusTooManyRequests, http.StatusOK)
+	server.header.Set("Retry-After", "3600")
+	client := newTestClient(server.URL)
+
+	err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil)
+	if !IsRetryable(err) {
+		t.Fatalf("error = %v, want a retryable error", err)
+	}
+	if server.count() != 1 || len(*slept) != 0 {
+		t.Errorf("sent %d requests and slept %v, want to give up beyond MaxDelay", server.count(), *slept)
+	}
+}
+
+func TestParseRetryAfterDate(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	header := http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}
+	if got := parseRetryAfter(header, now); got != 90*time.Second {
+		t.Errorf("parseRetryAfter = %s, want 1m30s", got)
+	}
+	header.Set("Retry-After", "soon")
+	if got := parseRetryAfter(header, now); got != 0 {
+		t.Errorf("parseRetryAfter(soon) = %s, want 0", got)
+	}
+}
+
+func TestNoRetryClientError(t *testing.T) {
+	slept := recordSleeps(t)
+	server := newTestServer(t, http.StatusBadRequest, http.StatusOK)
+	client := newTestClient(server.URL)
+
+	err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("error = %v, want a non-retryable HTTP 400", err)
+	}
+	if server.count() != 1 || len(*slept) != 0 {
+		t.Errorf("sent %d requests, want 1", server.count())
+	}
+}
+
+func TestNoRetryUndecodableResponse(t *testing.T) {
+	recordSleeps(t)
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{"id": "abc"`))
+	}))
+	defer server.Close()
+	client := newTestClient(server.URL)
+
+	var out map[string]interface{}
+	err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, &out)
+	if err == nil || IsRetryable(err) {
+		t.Fatalf("error = %v, want a non-retryable decode error", err)
+	}
+	if requests != 1 {
+		t.Errorf("sent %d requests, want the created record not to be sent again", requests)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	recordSleeps(t)
+	var mu sync.Mutex
+	requests := 0
+	unblock := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		first := requests == 1
+		mu.Unlock()
+
+		if first {
+			<-unblock
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+	defer close(unblock)
+
+	client := newTestClient(server.URL)
+	client.Timeout = 50 * time.Millisecond
+	client.Retry.MaxAttempts = 1
+
+	start := time.Now()
+	err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil)
+	if err == nil || !IsRetryable(err) {
+		t.Fatalf("error = %v, want a retryable timeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("request took %s, want the 50ms timeout to fire", elapsed)
+	}
+
+	// The timeout applies to every attempt, not to all of them
+	if err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil); err != nil {
+		t.Errorf("second request failed: %v", err)
+	}
+}
+
+func TestBreaker(t *testing.T) {
+	recordSleeps(t)
+	server := newTestServer(t, http.StatusServiceUnavailable)
+	client := newTestClient(server.URL)
+	client.Retry.MaxAttempts = 1
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	client.Breaker = NewBreaker(2, time.Minute)
+	client.Breaker.now = func() time.Time { return now }
+
+	ctx := context.Background()
+	send := func() error {
+		return client.Create(ctx, "load", map[string]interface{}{"a": 1}, nil)
+	}
+
+	// Two failures open the breaker
+	send()
+	send()
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error = %v, want ErrCircuitOpen", err)
+	}
+	if server.count() != 2 {
+		t.Errorf("server got %d requests, want none while open", server.count())
+	}
+	if !IsRetryable(ErrCircuitOpen) {
+		t.Error("ErrCircuitOpen is not retryable, writes would not be queued")
+	}
+
+	// After the cooldown a single trial request is let through
+	now = now.Add(time.Minute)
+	if err := client.Breaker.allow(); err != nil {
+		t.Fatalf("trial request not allowed: %v", err)
+	}
+	if err := client.Breaker.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("second request during the trial: %v, want ErrCircuitOpen", err)
+	}
+	client.Breaker.release()
+
+	// A failing trial opens the breaker again
+	if err := send(); errors.Is(err, ErrCircuitOpen) || err == nil {
+		t.Fatalf("trial error = %v, want the server error", err)
+	}
+	if err := send(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("error after failed trial = %v, want ErrCircuitOpen", err)
+	}
+
+	// A successful trial closes it
+	now = now.Add(time.Minute)
+	server.mu.Lock()
+	server.statuses = []int{http.StatusOK}
+	server.mu.Unlock()
+	if err := send(); err != nil {
+		t.Fatalf("trial failed: %v", err)
+	}
+	if err := send(); err != nil {
+		t.Errorf("request after successful trial failed: %v", err)
+	}
+}
+
+func TestBreakerCancelledTrial(t *testing.T) {
+	recordSleeps(t)
+	server := newTestServer(t, http.StatusServiceUnavailable)
+	client := newTestClient(server.URL)
+	client.Retry.MaxAttempts = 1
+
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	client.Breaker = NewBreaker(1, time.Minute)
+	client.Breaker.now = func() time.Time { return now }
+
+	client.Create(context.Background(), "load", nil, nil)
+	now = now.Add(time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client.Create(ctx, "load", nil, nil)
+
+	if err := client.Breaker.allow(); err != nil {
+		t.Errorf("breaker stuck after a cancelled trial: %v", err)
+	}
+}
+
+func TestRetryableTransportErrors(t *testing.T) {
+	tlsServer := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{}`))
+	}))
+	defer tlsServer.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	closed.Close()
+
+	tests := []struct {
+		name      string
+		url       string
+		client    *http.Client
+		retryable bool
+	}{
+		// The default client does not trust the test certificate
+		{"unknown authority", tlsServer.URL, nil, false},
+		// The test certificate is issued for 127.0.0.1 and example.com
+		{"hostname", strings.Replace(tlsServer.URL, "127.0.0.1", "localhost", 1), tlsServer.Client(), false},
+		{"unsupported scheme", "ftp://example.com", nil, false},
+		{"malformed URL", "http://[::1", nil, false},
+		{"connection refused", closed.URL, nil, true},
+	}
+
+	for _, test := range tests {
+		slept := recordSleeps(t)
+		client := newTestClient(test.url)
+		if test.client != nil {
+			client.HTTPClient = test.client
+		}
+
+		err := client.Create(context.Background(), "load", map[string]interface{}{"a": 1}, nil)
+		if err == nil {
+			t.Errorf("%s: Create succeeded, want an error", test.name)
+			continue
+		}
+		if IsRetryable(err) != test.retryable {
+			t.Errorf("%s: IsRetryable(%v) = %v, want %v", test.name, err, !test.retryable, test.retryable)
+		}
+		retries := 0
+		if test.retryable {
+			retries = DefaultRetryPolicy.MaxAttempts - 1
+		}
+		if len(*slept) != retries {
+			t.Errorf("%s: slept %v, want %d retries", test.name, *slept, retries)
+		}
+	}
+}
+
+func TestCertificateErrorsNotRetryable(t *testing.T) {
+	tests := map[string]error{
+		"expired":           x509.CertificateInvalidError{Reason: x509.Expired},
+		"unknown authority": x509.UnknownAuthorityError{},
+		"hostname":          x509.HostnameError{Host: "example.com"},
+		"verification":      &tls.CertificateVerificationError{Err: errors.New("bad chain")},
+	}
+
+	for name, certErr := range tests {
+		err := &url.Error{Op: "Post", URL: "https://example.com", Err: fmt.Errorf("tls: %w", certErr)}
+		if IsRetryable(err) {
+			t.Errorf("%s: IsRetryable(%v) = true, want false", name, err)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Package rdap looks up domain registration data with the Registration Data
+// Access Protocol (RFC 9083) and finds the RDAP server of a top level domain
+// in the IANA bootstrap registry (RFC 9224).
+package rdap
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// DefaultBootstrapURL is the IANA bootstrap registry for domain names.
+const DefaultBootstrapURL = "https://data.iana.org/rdap/dns.json"
+
+// Bootstrap is the bootstrap registry. Each service is a pair of a list of
+// top level domains and the base URLs of their RDAP servers.
+type Bootstrap struct {
+	Publication string       `json:"publication"`
+	Services    [][][]string `json:"services"`
+}
+
+// FetchBootstrap downloads the bootstrap registry from url.
+func FetchBootstrap(ctx context.Context, client *http.Client, url string) (*Bootstrap, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch bootstrap registry: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch bootstrap registry: HTTP %d", resp.StatusCode)
+	}
+
+	var bootstrap Bootstrap
+	if err := json.NewDecoder(resp.Body).Decode(&bootstrap); err != nil {
+		return nil, fmt.Errorf("failed to parse bootstrap registry: %v", err)
+	}
+	return &bootstrap, nil
+}
+
+// ServerFor returns the RDAP base URL responsible for domain. The longest
+// matching entry wins and HTTPS URLs are preferred.
+func (b *Bootstrap) ServerFor(domain string) (string, bool) {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+
+	best, bestLength := "", -1
+	for _, service := range b.Services {
+		if len(service) != 2 || len(service[1]) == 0 {
+			continue
+		}
+		for _, suffix := range service[0] {
+			suffix = strings.ToLower(suffix)
+			if domain != suffix && !strings.HasSuffix(domain, "."+suffix) {
+				continue
+			}
+			if len(suffix) > bestLength {
+				best, bestLength = preferHTTPS(service[1]), len(suffix)
+			}
+		}
+	}
+	return best, bestLength >= 0
+}
+
+// preferHTTPS returns the first HTTPS URL, or the first URL without one.
+func preferHTTPS(urls []string) string {
+	for _, url := range urls {
+		if strings.HasPrefix(url, "https://") {
+			return url
+		}
+	}
+	return urls[0]
+}
@@ -0,0 +1,157 @@
+package rdap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned when the RDAP server has no record of a domain.
+var ErrNotFound = errors.New("domain not found")
+
+// ErrNoServer is returned when no RDAP server is known for a domain.
+var ErrNoServer = errors.New("no RDAP server for domain")
+
+// Client looks up domains on RDAP servers.
+type Client struct {
+	HTTPClient *http.Client
+
+	// Server is queried for every domain when set, instead of the server
+	// listed in Bootstrap. It is meant for a local stand-in server.
+	Server string
+
+	// Bootstrap maps top level domains to their RDAP servers.
+	Bootstrap *Bootstrap
+}
+
+// Registration is the registration data of a domain.
+type Registration struct {
+	Domain     string    `json:"domain"`
+	Registrar  string    `json:"registrar"`
+	Status     []string  `json:"status"`
+	Registered time.Time `json:"registered"`
+	Expires    time.Time `json:"expires"`
+}
+
+// domainResponse is the part of an RDAP domain object the client uses.
+type domainResponse struct {
+	LDHName  string   `json:"ldhName"`
+	Status   []string `json:"status"`
+	Events   []event  `json:"events"`
+	Entities []entity `json:"entities"`
+}
+
+type event struct {
+	Action string `json:"eventAction"`
+	Date   string `json:"eventDate"`
+}
+
+type entity struct {
+	Handle     string        `json:"handle"`
+	Roles      []string      `json:"roles"`
+	VCardArray []interface{} `json:"vcardArray"`
+}
+
+// Domain looks up the registration of domain.
+func (c *Client) Domain(ctx context.Context, domain string) (*Registration, error) {
+	base := c.Server
+	if base == "" {
+		if c.Bootstrap == nil {
+			return nil, ErrNoServer
+		}
+		var ok bool
+		if base, ok = c.Bootstrap.ServerFor(domain); !ok {
+			return nil, ErrNoServer
+		}
+	}
+	if !strings.HasSuffix(base, "/") {
+		base += "/"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"domain/"+url.PathEscape(domain), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/rdap+json")
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query RDAP for %s: %v", domain, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, fmt.Errorf("%s: %w", domain, ErrNotFound)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("failed to query RDAP for %s: HTTP %d", domain, resp.StatusCode)
+	}
+
+	var body domainResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return nil, fmt.Errorf("failed to parse RDAP response for %s: %v", domain, err)
+	}
+
+	return body.registration(domain), nil
+}
+
+// registration extracts the registration data from the response.
+func (r domainResponse) registration(domain string) *Registration {
+	registration := &Registration{
+		Domain: strings.ToLower(r.LDHName),
+		Status: r.Status,
+	}
+	if registration.Domain == "" {
+		registration.Domain = domain
+	}
+
+	for _, event := range r.Events {
+		date, err := time.Parse(time.RFC3339, event.Date)
+		if err != nil {
+			continue
+		}
+		switch event.Action {
+		case "registration":
+			registration.Registered = date
+		case "expiration":
+			registration.Expires = date
+		}
+	}
+
+	for _, entity := range r.Entities {
+		for _, role := range entity.Roles {
+			if role == "registrar" {
+				registration.Registrar = entity.name()
+			}
+		}
+	}
+
+	return registration
+}
+
+// name returns the formatted name (fn) of the entity's vCard, or its handle.
+// A jCard (RFC 7095) is ["vcard", [[name, params, type, value], ...]].
+func (e entity) name() string {
+	if len(e.VCardArray) == 2 {
+		properties, _ := e.VCardArray[1].([]interface{})
+		for _, property := range properties {
+			fields, _ := property.([]interface{})
+			if len(fields) < 4 || fields[0] != "fn" {
+				continue
+			}
+			if name, ok := fields[3].(string); ok && name != "" {
+				return name
+			}
+		}
+	}
+	return e.Handle
+}
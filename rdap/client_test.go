@@ -0,0 +1,150 @@
+package rdap
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newServer starts a stand-in RDAP server. It serves the bootstrap registry
+// at /bootstrap.json, mapping "com" to itself, and the domain objects of
+// domains at /rdap/domain/<name>.
+func newServer(t *testing.T, domains map[string]string) *httptest.Server {
+	t.Helper()
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/bootstrap.json" {
+			json.NewEncoder(w).Encode(Bootstrap{
+				Publication: "2024-05-01T00:00:00Z",
+				Services: [][][]string{
+					{{"com"}, {server.URL + "/rdap/"}},
+				},
+			})
+			return
+		}
+
+		body, ok := domains[strings.TrimPrefix(r.URL.Path, "/rdap/domain/")]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/rdap+json")
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestServerFor(t *testing.T) {
+	bootstrap := &Bootstrap{Services: [][][]string{
+		{{"uk"}, {"https://rdap.uk.example/"}},
+		{{"co.uk", "org.uk"}, {"https://rdap.co-uk.example/"}},
+		{{"net"}, {"http://rdap.net.example/", "https://rdap.net.example/"}},
+		{{"dev"}, {"http://rdap.dev.example/"}},
+	}}
+
+	tests := []struct {
+		domain string
+		want   string
+		ok     bool
+	}{
+		{"shop.co.uk", "https://rdap.co-uk.example/", true},
+		{"WWW.Shop.CO.UK.", "https://rdap.co-uk.example/", true},
+		{"shop.uk", "https://rdap.uk.example/", true},
+		{"example.net", "https://rdap.net.example/", true},
+		{"example.dev", "http://rdap.dev.example/", true},
+		{"example.org", "", false},
+		{"notuk", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := bootstrap.ServerFor(tt.domain)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("ServerFor(%q) = %q, %v, want %q, %v", tt.domain, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDomain(t *testing.T) {
+	server := newServer(t, map[string]string{
+		"example.com": `{
+			"objectClassName": "domain",
+			"ldhName": "EXAMPLE.COM",
+			"status": ["client transfer prohibited"],
+			"events": [
+				{"eventAction": "registration", "eventDate": "1995-08-14T04:00:00Z"},
+				{"eventAction": "expiration", "eventDate": "2025-08-13T04:00:00Z"},
+				{"eventAction": "last changed", "eventDate": "not a date"}
+			],
+			"entities": [
+				{"handle": "R-1", "roles": ["technical"], "vcardArray": ["vcard", [["fn", {}, "text", "Not the registrar"]]]},
+				{"handle": "376", "roles": ["registrar"], "vcardArray": ["vcard", [
+					["version", {}, "text", "4.0"],
+					["fn", {}, "text", "Example Registrar, Inc."]
+				]]}
+			]
+		}`,
+		"handle.com": `{
+			"ldhName": "handle.com",
+			"entities": [{"handle": "HANDLE-REGISTRAR", "roles": ["registrar"]}]
+		}`,
+	})
+
+	ctx := context.Background()
+	bootstrap, err := FetchBootstrap(ctx, server.Client(), server.URL+"/bootstrap.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &Client{HTTPClient: server.Client(), Bootstrap: bootstrap}
+
+	registration, err := client.Domain(ctx, "example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registration.Domain != "example.com" {
+		t.Errorf("Domain = %q", registration.Domain)
+	}
+	if registration.Registrar != "Example Registrar, Inc." {
+		t.Errorf("Registrar = %q, want the fn of the registrar vCard", registration.Registrar)
+	}
+	if want := time.Date(2025, 8, 13, 4, 0, 0, 0, time.UTC); !registration.Expires.Equal(want) {
+		t.Errorf("Expires = %s, want %s", registration.Expires, want)
+	}
+	if want := time.Date(1995, 8, 14, 4, 0, 0, 0, time.UTC); !registration.Registered.Equal(want) {
+		t.Errorf("Registered = %s, want %s", registration.Registered, want)
+	}
+	if len(registration.Status) != 1 || registration.Status[0] != "client transfer prohibited" {
+		t.Errorf("Status = %v", registration.Status)
+	}
+
+	registration, err = client.Domain(ctx, "handle.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if registration.Registrar != "HANDLE-REGISTRAR" {
+		t.Errorf("Registrar = %q, want the handle without vCard", registration.Registrar)
+	}
+	if !registration.Expires.IsZero() {
+		t.Errorf("Expires = %s without expiration event", registration.Expires)
+	}
+}
+
+func TestDomainErrors(t *testing.T) {
+	server := newServer(t, nil)
+	ctx := context.Background()
+
+	client := &Client{HTTPClient: server.Client(), Server: server.URL + "/rdap"}
+	if _, err := client.Domain(ctx, "missing.com"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Domain(missing.com) error = %v, want ErrNotFound", err)
+	}
+
+	client = &Client{HTTPClient: server.Client(), Bootstrap: &Bootstrap{}}
+	if _, err := client.Domain(ctx, "example.org"); !errors.Is(err, ErrNoServer) {
+		t.Errorf("Domain(example.org) error = %v, want ErrNoServer", err)
+	}
+}
@@ -0,0 +1,155 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+	} {
+		if _, err := ParseCron(spec); err == nil {
+			t.Errorf("ParseCron(%q) succeeded, want an error", spec)
+		}
+	}
+}
+
+func TestCronNext(t *testing.T) {
+	tests := []struct {
+		spec string
+		from string
+		want string
+	}{
+		{"* * * * *", "2024-05-01 12:00", "2024-05-01 12:01"},
+		{"*/15 * * * *", "2024-05-01 12:07", "2024-05-01 12:15"},
+		{"0 * * * *", "2024-05-01 12:00", "2024-05-01 13:00"},
+		{"0 3 * * *", "2024-05-01 12:00", "2024-05-02 03:00"},
+		{"30 2 1 * *", "2024-05-01 12:00", "2024-06-01 02:30"},
+		{"0 0 * * 0", "2024-05-01 12:00", "2024-05-05 00:00"},
+		{"0 0 * * 7", "2024-05-01 12:00", "2024-05-05 00:00"},
+		{"0 0 * * 1-5", "2024-05-03 12:00", "2024-05-06 00:00"},
+		{"5/20 * * * *", "2024-05-01 12:06", "2024-05-01 12:25"},
+		{"0,30 9-10 * * *", "2024-05-01 10:30", "2024-05-02 09:00"},
+		{"0 0 29 2 *", "2024-03-01 00:00", "2028-02-29 00:00"},
+		// Both day fields restricted: either one matches.
+		{"0 0 13 * 5", "2024-05-01 00:00", "2024-05-03 00:00"},
+		{"0 0 31 12 *", "2024-12-31 00:00", "2025-12-31 00:00"},
+	}
+
+	for _, tt := range tests {
+		c, err := ParseCron(tt.spec)
+		if err != nil {
+			t.Fatalf("ParseCron(%q): %v", tt.spec, err)
+		}
+		if got := c.Next(date(tt.from)); !got.Equal(date(tt.want)) {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.spec, tt.from, got.Format("2006-01-02 15:04"), tt.want)
+		}
+	}
+}
+
+func TestCronNextNever(t *testing.T) {
+	c, err := ParseCron("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := c.Next(date("2024-01-01 00:00")); !got.IsZero() {
+		t.Errorf("Next = %s, want the zero time", got)
+	}
+}
+
+func TestParse(t *testing.T) {
+	if s, err := Parse("15m"); err != nil || s != (Every{Interval: 15 * time.Minute}) {
+		t.Errorf("Parse(15m) = %v, %v", s, err)
+	}
+	if _, err := Parse("100ms"); err == nil {
+		t.Error("Parse(100ms) succeeded, want an error")
+	}
+
+	s, err := Parse("@daily")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(date("2024-05-01 12:00")); !got.Equal(date("2024-05-02 00:00")) {
+		t.Errorf("@daily next = %s", got)
+	}
+}
+
+func TestDue(t *testing.T) {
+	tests := []struct {
+		spec string
+		now  time.Time
+		want bool
+	}{
+		{"1h", date("2024-05-01 12:00").Add(42 * time.Second), true},
+		{"1h", date("2024-05-01 12:01"), false},
+		{"5m", date("2024-05-01 12:05"), true},
+		{"30s", date("2024-05-01 12:07").Add(31 * time.Second), true},
+		{"0 3 * * *", date("2024-05-01 03:00").Add(59 * time.Second), true},
+		{"0 3 * * *", date("2024-05-01 03:01"), false},
+		{"*/10 * * * *", date("2024-05-01 12:20"), true},
+		{"*/10 * * * *", date("2024-05-01 12:21"), false},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+		if got := Due(s, tt.now); got != tt.want {
+			t.Errorf("Due(%q, %s) = %v, want %v", tt.spec, tt.now.Format(time.RFC3339), got, tt.want)
+		}
+	}
+}
+
+func TestMissed(t *testing.T) {
+	tests := []struct {
+		spec string
+		last string
+		now  string
+		want string // empty for none
+	}{
+		// Cron started the agent late: the 12:00 activation is caught up.
+		{"1h", "2024-05-01 11:00", "2024-05-01 12:03", "2024-05-01 12:00"},
+		{"1h", "2024-05-01 12:00", "2024-05-01 12:03", ""},
+		// Several missed activations collapse into the latest one.
+		{"1h", "2024-05-01 08:00", "2024-05-01 12:03", "2024-05-01 12:00"},
+		{"0 3 * * *", "2024-04-28 03:00", "2024-05-01 12:00", "2024-05-01 03:00"},
+		{"0 3 * * *", "2024-05-01 03:00", "2024-05-01 12:00", ""},
+		{"*/10 * * * *", "2024-05-01 12:10", "2024-05-01 12:20", "2024-05-01 12:20"},
+	}
+
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Fatalf("Parse(%q): %v", tt.spec, err)
+		}
+
+		got := Missed(s, date(tt.last), date(tt.now))
+		var want time.Time
+		if tt.want != "" {
+			want = date(tt.want)
+		}
+		if !got.Equal(want) {
+			t.Errorf("Missed(%q, %s, %s) = %s, want %s", tt.spec, tt.last, tt.now, got, want)
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Package scheduler runs jobs on interval or cron schedules.
+package scheduler
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Schedule computes when a job runs next.
+type Schedule interface {
+	// Next returns the first activation strictly after t.
+	Next(t time.Time) time.Time
+}
+
+// Every runs a job at a fixed interval. Activations are aligned to the UTC
+// clock, so "1h" always fires at the top of the hour no matter when the
+// agent was started.
+type Every struct {
+	Interval time.Duration
+}
+
+// Next returns the next multiple of the interval after t.
+func (e Every) Next(t time.Time) time.Time {
+	return t.Truncate(e.Interval).Add(e.Interval)
+}
+
+// Parse parses a schedule specification. It accepts a Go duration of at
+// least one second ("30s", "15m", "1h"), a five-field cron expression
+// ("0 3 * * *") or one of the shortcuts @hourly, @daily, @weekly and
+// @monthly.
+func Parse(spec string) (Schedule, error) {
+	spec = strings.TrimSpace(spec)
+
+	switch spec {
+	case "@hourly":
+		spec = "0 * * * *"
+	case "@daily", "@midnight":
+		spec = "0 0 * * *"
+	case "@weekly":
+		spec = "0 0 * * 0"
+	case "@monthly":
+		spec = "0 0 1 * *"
+	}
+
+	if interval, err := time.ParseDuration(spec); err == nil {
+		if interval < time.Second {
+			return nil, fmt.Errorf("interval %q is shorter than one second", spec)
+		}
+		return Every{Interval: interval}, nil
+	}
+
+	return ParseCron(spec)
+}
+
+// Due reports whether s has an activation within the minute containing t.
+// It lets one-shot runs started by the system cron decide which jobs to run.
+func Due(s Schedule, t time.Time) bool {
+	minute := t.Truncate(time.Minute)
+	next := s.Next(minute.Add(-time.Nanosecond))
+	return !next.IsZero() && next.Before(minute.Add(time.Minute))
+}
+
+// maxCatchUp bounds how far back Missed looks for activations.
+const maxCatchUp = 366 * 24 * time.Hour
+
+// Missed returns the latest activation of s after last and within the minute
+// containing now, or the zero time when there is none. One-shot runs use it
+// to catch up on activations missed because cron started them late or not
+// at all.
+func Missed(s Schedule, last, now time.Time) time.Time {
+	end := now.Truncate(time.Minute).Add(time.Minute)
+	if from := now.Add(-maxCatchUp); last.Before(from) {
+		last = from
+	}
+
+	if every, ok := s.(Every); ok {
+		latest := end.Add(-time.Nanosecond).Truncate(every.Interval)
+		if !latest.After(last) {
+			return time.Time{}
+		}
+		return latest
+	}
+
+	var latest time.Time
+	for next := s.Next(last); !next.IsZero() && next.Before(end); next = s.Next(next) {
+		latest = next
+	}
+	return latest
+}
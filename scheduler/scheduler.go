@@ -0,0 +1,78 @@
+package scheduler
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Job is a unit of work run on its own schedule.
+type Job struct {
+	Name     string
+	Schedule Schedule
+
+	// Jitter delays every activation by a random duration in [0, Jitter),
+	// so a fleet of servers does not hit PocketBase in the same second.
+	Jitter time.Duration
+
+	Run func(ctx context.Context) error
+}
+
+// Run runs every job on its schedule until ctx is cancelled.
+//
+// Each job has its own goroutine and never overlaps with itself. When a run
+// takes longer than the interval, or the host was suspended, the missed
+// activations are collapsed into a single run instead of being replayed back
+// to back, so no activation is silently dropped and none runs twice.
+func Run(ctx context.Context, jobs []Job) {
+	var wg sync.WaitGroup
+	for _, job := range jobs {
+		wg.Add(1)
+		go func(job Job) {
+			defer wg.Done()
+			runJob(ctx, job)
+		}(job)
+	}
+	wg.Wait()
+}
+
+func runJob(ctx context.Context, job Job) {
+	next := job.Schedule.Next(time.Now())
+
+	for {
+		if next.IsZero() {
+			log.Printf("Job %s has no upcoming activation, stopping it", job.Name)
+			return
+		}
+
+		wait := time.Until(next)
+		if job.Jitter > 0 {
+			wait += time.Duration(rand.Int63n(int64(job.Jitter)))
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+
+		start := time.Now()
+		if err := job.Run(ctx); err != nil {
+			log.Printf("Job %s failed: %v", job.Name, err)
+		} else {
+			log.Printf("Job %s finished in %s", job.Name, time.Since(start).Round(time.Millisecond))
+		}
+
+		// Advance from the activation that just ran. If we are already past
+		// the following one, skip to the first activation still ahead.
+		next = job.Schedule.Next(next)
+		if now := time.Now(); !next.IsZero() && next.Before(now) {
+			log.Printf("Job %s missed activations up to %s, running once to catch up", job.Name, now.Format(time.RFC3339))
+			next = now
+		}
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !unix
+
+package spool
+
+import "os"
+
+// lockFile is a no-op without flock(2), only the process local lock applies.
+func lockFile(file *os.File) error {
+	return nil
+}
+
+func unlockFile(file *os.File) {}
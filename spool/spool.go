@@ -0,0 +1,408 @@
+// Package spool queues writes that could not be sent to PocketBase in an
+// append-only file and replays them in order once it is reachable again.
+package spool
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Defaults for the caps of a spool.
+const (
+	DefaultMaxEntries = 10000
+	DefaultMaxAge     = 7 * 24 * time.Hour
+)
+
+// Backoff between failed replays, doubled after every failure.
+const (
+	minBackoff = 30 * time.Second
+	maxBackoff = 30 * time.Minute
+)
+
+// Actions of an Op.
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionUpsert = "upsert"
+)
+
+// Op is a queued write. Create adds a record to Collection, Update changes
+// the record ID and Upsert updates the first record matching Filter or
+// creates one with the ID.
+type Op struct {
+	Time       time.Time       `json:"time"`
+	Action     string          `json:"action"`
+	Collection string          `json:"collection"`
+	ID         string          `json:"id,omitempty"`
+	Filter     string          `json:"filter,omitempty"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// key identifies the record an upsert writes. A later upsert of the same
+// record replaces an earlier one that has not been replayed yet.
+func (op Op) key() string {
+	if op.Action != ActionUpsert {
+		return ""
+	}
+	return op.Collection + "\x00" + op.Filter
+}
+
+// Spool is a queue of writes in Dir. It is safe for concurrent use, also by
+// several processes sharing Dir.
+type Spool struct {
+	Dir string
+
+	// MaxEntries and MaxAge cap the queue. The oldest writes are dropped
+	// first. DefaultMaxEntries and DefaultMaxAge are used when zero.
+	MaxEntries int
+	MaxAge     time.Duration
+
+	// mu guards the files, replaying serialises replays, which send the
+	// writes without holding mu.
+	mu        sync.Mutex
+	replaying sync.Mutex
+}
+
+// backoff is the persisted state of failed replays.
+type backoff struct {
+	Failures int       `json:"failures"`
+	Next     time.Time `json:"next"`
+}
+
+func (s *Spool) path(name string) string {
+	return filepath.Join(s.Dir, name)
+}
+
+// Add appends op to the queue.
+func (s *Spool) Add(op Op) error {
+	if op.Time.IsZero() {
+		op.Time = time.Now().UTC()
+	}
+	line, err := json.Marshal(op)
+	if err != nil {
+		return fmt.Errorf("failed to encode spooled write: %v", err)
+	}
+
+	unlock, err := s.lock(&s.mu, "spool.lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	file, err := os.OpenFile(s.path("spool.jsonl"), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open spool: %v", err)
+	}
+	_, err = file.Write(append(line, '\n'))
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write spool: %v", err)
+	}
+
+	return s.enforceCaps()
+}
+
+// Replay sends the queued writes in order with apply. It stops at the first
+// write apply fails and backs off exponentially before the next attempt;
+// calls during the backoff return immediately. apply should return nil for
+// writes that can never succeed, which are then dropped.
+//
+// The writes are sent without locking the queue, so writes added meanwhile
+// are kept behind them.
+func (s *Spool) Replay(ctx context.Context, apply func(context.Context, Op) error) (int, error) {
+	// Only one replay at a time, so that no write is sent twice
+	unlock, err := s.lock(&s.replaying, "spool-replay.lock")
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	state, ops, err := s.snapshot()
+	if err != nil || len(ops) == 0 {
+		return 0, err
+	}
+
+	// Only the last of several upserts of the same record is sent
+	last := map[string]int{}
+	for i, op := range ops {
+		if key := op.key(); key != "" {
+			last[key] = i
+		}
+	}
+
+	sent := 0
+	for i, op := range ops {
+		if key := op.key(); key != "" && last[key] != i {
+			continue
+		}
+
+		if err := apply(ctx, op); err != nil {
+			return sent, s.commit(ops[:i], state, err)
+		}
+		sent++
+	}
+	return sent, s.commit(ops, state, nil)
+}
+
+// snapshot returns the backoff state and the queued writes, or no writes
+// during the backoff.
+func (s *Spool) snapshot() (backoff, []Op, error) {
+	unlock, err := s.lock(&s.mu, "spool.lock")
+	if err != nil {
+		return backoff{}, nil, err
+	}
+	defer unlock()
+
+	var state backoff
+	if err := readJSON(s.path("spool-backoff.json"), &state); err != nil {
+		log.Printf("Ignoring spool backoff: %v", err)
+	}
+	if time.Now().Before(state.Next) {
+		return state, nil, nil
+	}
+
+	ops, err := s.read()
+	if err != nil {
+		return state, nil, err
+	}
+	if trimmed := s.trim(ops); len(trimmed) != len(ops) {
+		return state, trimmed, s.write(trimmed)
+	}
+	return state, ops, nil
+}
+
+// commit removes the replayed writes done from the queue and updates the
+// backoff with the outcome of the replay, replayErr when it stopped.
+func (s *Spool) commit(done []Op, state backoff, replayErr error) error {
+	unlock, err := s.lock(&s.mu, "spool.lock")
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	ops, err := s.read()
+	if err != nil {
+		return err
+	}
+	ops = s.trim(remove(ops, done))
+	if err := s.write(ops); err != nil {
+		return err
+	}
+
+	if replayErr == nil {
+		if err := os.Remove(s.path("spool-backoff.json")); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Error resetting spool backoff: %v", err)
+		}
+		return nil
+	}
+
+	state.Failures++
+	delay := minBackoff << min(state.Failures-1, 10)
+	if delay > maxBackoff {
+		delay = maxBackoff
+	}
+	delay += time.Duration(rand.Int63n(int64(delay / 4)))
+	state.Next = time.Now().Add(delay)
+	if err := writeJSON(s.path("spool-backoff.json"), state); err != nil {
+		log.Printf("Error saving spool backoff: %v", err)
+	}
+	return fmt.Errorf("replay stopped, %d writes left, next attempt in %s: %w", len(ops), delay.Round(time.Second), replayErr)
+}
+
+// remove returns ops without the writes of done. The other writes, e.g.
+// those added during a replay, keep their order.
+func remove(ops, done []Op) []Op {
+	removed := map[string]int{}
+	for _, op := range done {
+		removed[op.line()]++
+	}
+
+	var kept []Op
+	for _, op := range ops {
+		if line := op.line(); removed[line] > 0 {
+			removed[line]--
+			continue
+		}
+		kept = append(kept, op)
+	}
+	return kept
+}
+
+// line is the encoded op, identifying it in the queue.
+func (op Op) line() string {
+	line, _ := json.Marshal(op)
+	return string(line)
+}
+
+// Pending reports whether writes are queued. New writes have to be queued
+// behind them, or a replayed write could overwrite newer data.
+func (s *Spool) Pending() bool {
+	info, err := os.Stat(s.path("spool.jsonl"))
+	return err == nil && info.Size() > 0
+}
+
+// enforceCaps drops the writes beyond MaxEntries and MaxAge. The file is
+// only rewritten when something was dropped.
+func (s *Spool) enforceCaps() error {
+	ops, err := s.read()
+	if err != nil {
+		return err
+	}
+	if trimmed := s.trim(ops); len(trimmed) != len(ops) {
+		return s.write(trimmed)
+	}
+	return nil
+}
+
+// trim returns ops without the writes beyond MaxEntries and MaxAge.
+func (s *Spool) trim(ops []Op) []Op {
+	maxEntries, maxAge := s.MaxEntries, s.MaxAge
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	if maxAge <= 0 {
+		maxAge = DefaultMaxAge
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	first := 0
+	for first < len(ops) && ops[first].Time.Before(cutoff) {
+		first++
+	}
+	if len(ops)-first > maxEntries {
+		first = len(ops) - maxEntries
+	}
+
+	if first > 0 {
+		log.Printf("Dropped %d spooled writes over the size or age limit", first)
+	}
+	return ops[first:]
+}
+
+// read returns the queued writes. Lines that cannot be decoded, e.g. after a
+// crash during a write, are skipped.
+func (s *Spool) read() ([]Op, error) {
+	file, err := os.Open(s.path("spool.jsonl"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open spool: %v", err)
+	}
+	defer file.Close()
+
+	var ops []Op
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 16<<20)
+	for scanner.Scan() {
+		var op Op
+		if err := json.Unmarshal(scanner.Bytes(), &op); err != nil {
+			log.Printf("Skipping corrupt spool entry: %v", err)
+			continue
+		}
+		ops = append(ops, op)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read spool: %v", err)
+	}
+	return ops, nil
+}
+
+// write replaces the queue with ops atomically. No ops removes the file.
+func (s *Spool) write(ops []Op) error {
+	if len(ops) == 0 {
+		err := os.Remove(s.path("spool.jsonl"))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to clear spool: %v", err)
+		}
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(s.Dir, "spool.*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to write spool: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	writer := bufio.NewWriter(tmp)
+	encoder := json.NewEncoder(writer)
+	for _, op := range ops {
+		if err := encoder.Encode(op); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to write spool: %v", err)
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write spool: %v", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write spool: %v", err)
+	}
+
+	if err := os.Rename(tmp.Name(), s.path("spool.jsonl")); err != nil {
+		return fmt.Errorf("failed to write spool: %v", err)
+	}
+	return nil
+}
+
+// lock takes mu and the lock file name, serialising access within this
+// process and with other processes using the same directory.
+func (s *Spool) lock(mu *sync.Mutex, name string) (func(), error) {
+	mu.Lock()
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to create spool directory: %v", err)
+	}
+
+	file, err := os.OpenFile(s.path(name), os.O_RDWR|os.O_CREATE, 0o600)
+	if err != nil {
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to open spool lock: %v", err)
+	}
+	if err := lockFile(file); err != nil {
+		file.Close()
+		mu.Unlock()
+		return nil, fmt.Errorf("failed to lock spool: %v", err)
+	}
+
+	return func() {
+		unlockFile(file)
+		file.Close()
+		mu.Unlock()
+	}, nil
+}
+
+// readJSON decodes the file at path into v, leaving v untouched when the file
+// does not exist.
+func readJSON(path string, v interface{}) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// writeJSON encodes v to the file at path.
+func writeJSON(path string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
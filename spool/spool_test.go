@@ -0,0 +1,233 @@
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// testOp returns an op made age ago, its body naming the op.
+func testOp(action, name string, age time.Duration) Op {
+	return Op{
+		Time:       time.Now().Add(-age).UTC(),
+		Action:     action,
+		Collection: "test",
+		Filter:     "name = '" + name + "'",
+		Body:       json.RawMessage(fmt.Sprintf(`{"name":%q}`, name)),
+	}
+}
+
+// names returns the name in the body of every op.
+func names(ops []Op) []string {
+	var names []string
+	for _, op := range ops {
+		var body struct{ Name string }
+		json.Unmarshal(op.Body, &body)
+		names = append(names, body.Name)
+	}
+	return names
+}
+
+// replayAll replays s, recording the names of the ops sent.
+func replayAll(t *testing.T, s *Spool) []string {
+	t.Helper()
+	var sent []Op
+	_, err := s.Replay(context.Background(), func(ctx context.Context, op Op) error {
+		sent = append(sent, op)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Replay failed: %v", err)
+	}
+	return names(sent)
+}
+
+func TestTrim(t *testing.T) {
+	tests := []struct {
+		name       string
+		maxEntries int
+		maxAge     time.Duration
+		ages       []time.Duration
+		want       []string
+	}{
+		{"defaults", 0, 0, []time.Duration{8 * 24 * time.Hour, time.Hour, 0}, []string{"1", "2"}},
+		{"max entries", 2, 0, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour}, []string{"1", "2"}},
+		{"max age", 0, 90 * time.Minute, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour}, []string{"2"}},
+		{"both", 1, 90 * time.Minute, []time.Duration{2 * time.Hour, time.Hour, time.Minute}, []string{"2"}},
+		{"within caps", 3, 4 * time.Hour, []time.Duration{3 * time.Hour, 2 * time.Hour, time.Hour}, []string{"0", "1", "2"}},
+	}
+
+	for _, test := range tests {
+		s := &Spool{MaxEntries: test.maxEntries, MaxAge: test.maxAge}
+		var ops []Op
+		for i, age := range test.ages {
+			ops = append(ops, testOp(ActionCreate, fmt.Sprint(i), age))
+		}
+
+		if got := names(s.trim(ops)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: trim kept %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAddEnforcesCaps(t *testing.T) {
+	s := &Spool{Dir: t.TempDir(), MaxEntries: 2}
+	for i := 0; i < 4; i++ {
+		if err := s.Add(testOp(ActionCreate, fmt.Sprint(i), 0)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := replayAll(t, s), []string{"2", "3"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}
+
+func TestReplayDedup(t *testing.T) {
+	tests := []struct {
+		name string
+		ops  []Op
+		want []string
+	}{
+		{
+			name: "last upsert of a record",
+			ops:  []Op{testOp(ActionUpsert, "a", 0), testOp(ActionUpsert, "b", 0), testOp(ActionUpsert, "a", 0)},
+			want: []string{"b", "a"},
+		},
+		{
+			name: "creates are all sent",
+			ops:  []Op{testOp(ActionCreate, "a", 0), testOp(ActionCreate, "a", 0)},
+			want: []string{"a", "a"},
+		},
+		{
+			name: "updates are all sent",
+			ops:  []Op{testOp(ActionUpdate, "a", 0), testOp(ActionUpdate, "a", 0)},
+			want: []string{"a", "a"},
+		},
+		{
+			name: "other collection",
+			ops: []Op{
+				testOp(ActionUpsert, "a", 0),
+				func() Op { op := testOp(ActionUpsert, "a", 0); op.Collection = "other"; return op }(),
+			},
+			want: []string{"a", "a"},
+		},
+	}
+
+	for _, test := range tests {
+		s := &Spool{Dir: t.TempDir()}
+		for _, op := range test.ops {
+			if err := s.Add(op); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if got := replayAll(t, s); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("%s: replayed %v, want %v", test.name, got, test.want)
+		}
+		if s.Pending() {
+			t.Errorf("%s: writes pending after a successful replay", test.name)
+		}
+	}
+}
+
+func TestReplayBackoff(t *testing.T) {
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 30 * time.Second},
+		{1, time.Minute},
+		{4, 8 * time.Minute},
+		{6, 30 * time.Minute},
+		{20, 30 * time.Minute},
+	}
+
+	for _, test := range tests {
+		s := &Spool{Dir: t.TempDir()}
+		if err := writeJSON(s.path("spool-backoff.json"), backoff{Failures: test.failures}); err != nil {
+			t.Fatal(err)
+		}
+		s.Add(testOp(ActionCreate, "a", 0))
+		s.Add(testOp(ActionCreate, "b", 0))
+
+		errDown := errors.New("down")
+		start := time.Now()
+		sent, err := s.Replay(context.Background(), func(ctx context.Context, op Op) error {
+			if string(op.Body) == `{"name":"b"}` {
+				return errDown
+			}
+			return nil
+		})
+		if sent != 1 || !errors.Is(err, errDown) {
+			t.Errorf("%d failures: Replay() = %d, %v, want 1, %v", test.failures, sent, err, errDown)
+		}
+
+		var state backoff
+		if err := readJSON(s.path("spool-backoff.json"), &state); err != nil {
+			t.Fatal(err)
+		}
+		delay := state.Next.Sub(start)
+		if state.Failures != test.failures+1 || delay < test.want || delay > test.want*5/4+time.Second {
+			t.Errorf("%d failures: backoff %+v, delay %s, want %d failures and %s plus up to a quarter", test.failures, state, delay, test.failures+1, test.want)
+		}
+
+		// During the backoff nothing is sent
+		sent, err = s.Replay(context.Background(), func(ctx context.Context, op Op) error {
+			t.Errorf("%d failures: write sent during the backoff", test.failures)
+			return nil
+		})
+		if sent != 0 || err != nil {
+			t.Errorf("%d failures: Replay() during backoff = %d, %v, want 0, nil", test.failures, sent, err)
+		}
+
+		// After the backoff the failed write is sent again and the
+		// backoff is reset
+		state.Next = time.Now()
+		writeJSON(s.path("spool-backoff.json"), state)
+		if got, want := replayAll(t, s), []string{"b"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%d failures: replayed %v after the backoff, want %v", test.failures, got, want)
+		}
+		state = backoff{}
+		readJSON(s.path("spool-backoff.json"), &state)
+		if state.Failures != 0 {
+			t.Errorf("%d failures: backoff %+v after a successful replay, want none", test.failures, state)
+		}
+	}
+}
+
+// TestReplayKeepsNewWrites adds writes while a replay is sending, which
+// must neither block nor be lost.
+func TestReplayKeepsNewWrites(t *testing.T) {
+	s := &Spool{Dir: t.TempDir()}
+	s.Add(testOp(ActionCreate, "a", 0))
+	s.Add(testOp(ActionCreate, "b", 0))
+
+	added := 0
+	_, err := s.Replay(context.Background(), func(ctx context.Context, op Op) error {
+		added++
+		if err := s.Add(testOp(ActionCreate, fmt.Sprint("new", added), 0)); err != nil {
+			t.Fatal(err)
+		}
+		if added == 2 {
+			return errors.New("down")
+		}
+		return nil
+	})
+	if err == nil {
+		t.Fatal("Replay succeeded, want an error")
+	}
+
+	// Replay again right away
+	if err := os.Remove(s.path("spool-backoff.json")); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := replayAll(t, s), []string{"b", "new1", "new2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("replayed %v, want %v", got, want)
+	}
+}